@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS pet_events (
+  id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  pet_id        UUID NOT NULL REFERENCES pets(id) ON DELETE CASCADE,
+  type          STRING NOT NULL,
+  payload       JSONB NOT NULL DEFAULT '{}'::JSONB,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+  seen_at       TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_pet_events_pet_unseen ON pet_events(pet_id, created_at) WHERE seen_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS pet_events;
@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS wallets (
+  user_id       UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  coins         INT NOT NULL DEFAULT 0 CHECK (coins >= 0),
+  updated_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS inventory_items (
+  user_id       UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  item_id       STRING NOT NULL,
+  quantity      INT NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, item_id)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS inventory_items;
+DROP TABLE IF EXISTS wallets;
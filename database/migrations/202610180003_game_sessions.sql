@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS game_sessions (
+  id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id       UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  game_id       STRING NOT NULL,
+  status        STRING NOT NULL DEFAULT 'open',
+  score         INT,
+  started_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+  expires_at    TIMESTAMPTZ NOT NULL,
+  finished_at   TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_game_sessions_user_id ON game_sessions(user_id, started_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS game_sessions;
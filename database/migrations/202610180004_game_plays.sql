@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS game_plays (
+  id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id       UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  game_id       STRING NOT NULL,
+  score         INT NOT NULL,
+  duration_ms   INT NOT NULL DEFAULT 0,
+  mode          STRING NOT NULL DEFAULT 'solo',
+  opponent_id   UUID REFERENCES users(id) ON DELETE SET NULL,
+  outcome       STRING,
+  session_id    UUID,
+  started_at    TIMESTAMPTZ NOT NULL,
+  ended_at      TIMESTAMPTZ NOT NULL,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_game_plays_user_ended ON game_plays(user_id, ended_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_game_plays_user_game_ended ON game_plays(user_id, game_id, ended_at DESC, id DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS game_plays;
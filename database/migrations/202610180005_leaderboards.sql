@@ -0,0 +1,12 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+-- CockroachDB can't backfill a new column and write to it in the same transaction
+ALTER TABLE games ADD COLUMN IF NOT EXISTS high_score INT NOT NULL DEFAULT 0;
+UPDATE games SET high_score = COALESCE((attrs->>'high_score')::INT, 0) WHERE attrs ? 'high_score';
+CREATE INDEX IF NOT EXISTS idx_games_title_high_score ON games(title, high_score DESC) STORING (user_id);
+CREATE INDEX IF NOT EXISTS idx_game_plays_game_ended ON game_plays(game_id, ended_at) STORING (user_id, score);
+
+-- +goose Down
+DROP INDEX IF EXISTS game_plays@idx_game_plays_game_ended;
+DROP INDEX IF EXISTS games@idx_games_title_high_score;
+ALTER TABLE games DROP COLUMN IF EXISTS high_score;
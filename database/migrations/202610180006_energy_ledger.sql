@@ -0,0 +1,22 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+-- CockroachDB can't backfill a new column and write to it in the same transaction
+ALTER TABLE users ADD COLUMN IF NOT EXISTS energy INT NOT NULL DEFAULT 30;
+UPDATE users SET energy = LEAST(GREATEST((attrs->>'energy')::INT, 0), 100) WHERE attrs ? 'energy';
+ALTER TABLE users ADD CONSTRAINT chk_users_energy CHECK (energy BETWEEN 0 AND 100);
+
+CREATE TABLE IF NOT EXISTS energy_ledger (
+  id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id        UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  delta          INT NOT NULL,
+  reason         STRING NOT NULL,
+  ref_id         STRING,
+  balance_after  INT NOT NULL,
+  created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_energy_ledger_user_created ON energy_ledger(user_id, created_at DESC, id DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS energy_ledger;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS chk_users_energy;
+ALTER TABLE users DROP COLUMN IF EXISTS energy;
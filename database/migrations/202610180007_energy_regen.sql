@@ -0,0 +1,6 @@
+-- +goose Up
+-- Regeneration clock: energy regenerates for every full interval since this time
+ALTER TABLE users ADD COLUMN IF NOT EXISTS energy_regen_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS energy_regen_at;
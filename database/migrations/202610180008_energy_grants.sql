@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOL NOT NULL DEFAULT false;
+
+-- Admin corrections record who made them and why
+ALTER TABLE energy_ledger ADD COLUMN IF NOT EXISTS note STRING;
+ALTER TABLE energy_ledger ADD COLUMN IF NOT EXISTS actor_id UUID REFERENCES users(id) ON DELETE SET NULL;
+
+-- The daily bonus is granted at most once per user per day (ref_id is the UTC date)
+CREATE UNIQUE INDEX IF NOT EXISTS uid_energy_ledger_daily_bonus ON energy_ledger(user_id, ref_id) WHERE reason = 'daily_bonus';
+
+-- +goose Down
+DROP INDEX IF EXISTS energy_ledger@uid_energy_ledger_daily_bonus;
+ALTER TABLE energy_ledger DROP COLUMN IF EXISTS actor_id;
+ALTER TABLE energy_ledger DROP COLUMN IF EXISTS note;
+ALTER TABLE users DROP COLUMN IF EXISTS is_admin;
@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS habit_completions (
+  id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id       UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  habit_id      UUID REFERENCES habits(id) ON DELETE SET NULL,
+  completed_on  DATE NOT NULL,
+  completed_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_habit_completions_user_on ON habit_completions(user_id, completed_on DESC);
+
+CREATE TABLE IF NOT EXISTS user_achievements (
+  user_id         UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  achievement_id  STRING NOT NULL,
+  unlocked_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, achievement_id)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS user_achievements;
+DROP TABLE IF EXISTS habit_completions;
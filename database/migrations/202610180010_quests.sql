@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS user_quests (
+  id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id        UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  quest_id       STRING NOT NULL,
+  period         STRING NOT NULL,
+  period_start   TIMESTAMPTZ NOT NULL,
+  expires_at     TIMESTAMPTZ NOT NULL,
+  progress       INT NOT NULL DEFAULT 0,
+  target         INT NOT NULL,
+  reward_coins   INT NOT NULL DEFAULT 0,
+  reward_energy  INT NOT NULL DEFAULT 0,
+  claimed_at     TIMESTAMPTZ,
+  created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (user_id, period_start, quest_id)
+);
+CREATE INDEX IF NOT EXISTS idx_user_quests_user_expires ON user_quests(user_id, expires_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS user_quests;
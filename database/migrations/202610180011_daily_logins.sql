@@ -0,0 +1,20 @@
+-- +goose Up
+-- IANA name; login days are counted in the user's local time
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone STRING NOT NULL DEFAULT 'UTC';
+
+CREATE TABLE IF NOT EXISTS daily_logins (
+  user_id        UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  day            DATE NOT NULL,
+  streak         INT NOT NULL,
+  first_seen_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  claimed_at     TIMESTAMPTZ,
+  PRIMARY KEY (user_id, day)
+);
+
+-- The login reward is granted at most once per user per day (ref_id is the local date)
+CREATE UNIQUE INDEX IF NOT EXISTS uid_energy_ledger_daily_login ON energy_ledger(user_id, ref_id) WHERE reason = 'daily_login';
+
+-- +goose Down
+DROP INDEX IF EXISTS energy_ledger@uid_energy_ledger_daily_login;
+DROP TABLE IF EXISTS daily_logins;
+ALTER TABLE users DROP COLUMN IF EXISTS timezone;
@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS user_ratings (
+  user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  game_id     STRING NOT NULL,
+  rating      INT NOT NULL DEFAULT 1200,
+  matches     INT NOT NULL DEFAULT 0,
+  updated_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, game_id)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS user_ratings;
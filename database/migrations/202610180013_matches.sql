@@ -0,0 +1,33 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS matches (
+  id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  game_id      STRING NOT NULL,
+  room_id      UUID NOT NULL,
+  private      BOOL NOT NULL DEFAULT false,
+  rounds       INT NOT NULL DEFAULT 0,
+  winner_id    UUID REFERENCES users(id) ON DELETE SET NULL,
+  started_at   TIMESTAMPTZ NOT NULL,
+  ended_at     TIMESTAMPTZ NOT NULL,
+  duration_ms  INT NOT NULL DEFAULT 0,
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- One row per participant; place 1 is the best
+CREATE TABLE IF NOT EXISTS match_players (
+  match_id  UUID NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+  place     INT NOT NULL,
+  user_id   UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  score     INT NOT NULL,
+  outcome   STRING NOT NULL,
+  forfeit   BOOL NOT NULL DEFAULT false,
+  ended_at  TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (match_id, place)
+);
+CREATE INDEX IF NOT EXISTS idx_match_players_user ON match_players(user_id);
+
+ALTER TABLE game_plays ADD COLUMN IF NOT EXISTS match_id UUID REFERENCES matches(id) ON DELETE SET NULL;
+
+-- +goose Down
+ALTER TABLE game_plays DROP COLUMN IF EXISTS match_id;
+DROP TABLE IF EXISTS match_players;
+DROP TABLE IF EXISTS matches;
@@ -0,0 +1,243 @@
+package achievement
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"fsd-backend/internal/pet"
+)
+
+//go:embed achievements.json
+var embeddedDefinitions []byte
+
+// Criteria types
+const (
+	// HabitCompletions counts every habit marked done
+	HabitCompletions = "habit_completions"
+	// HabitStreak is the current run of consecutive days with a completed habit
+	HabitStreak = "habit_streak"
+	// GamePlays counts finished plays, of Game if set
+	GamePlays = "game_plays"
+	// BestScore is the best score in Game
+	BestScore = "best_score"
+	// MultiplayerWins counts multiplayer wins, in Game if set
+	MultiplayerWins = "multiplayer_wins"
+	// PetLevel is the level of the user's pet
+	PetLevel = "pet_level"
+	// PetStat is the current value of Stat on the user's pet
+	PetStat = "pet_stat"
+)
+
+// Source identifies the data a criteria type is computed from
+type Source int
+
+const (
+	SourceHabits Source = iota
+	SourcePlays
+	SourcePet
+)
+
+var criteriaSources = map[string]Source{
+	HabitCompletions: SourceHabits,
+	HabitStreak:      SourceHabits,
+	GamePlays:        SourcePlays,
+	BestScore:        SourcePlays,
+	MultiplayerWins:  SourcePlays,
+	PetLevel:         SourcePet,
+	PetStat:          SourcePet,
+}
+
+// Criteria is the condition for unlocking an achievement: the value of Type
+// (optionally narrowed to Game or Stat) must reach Target
+type Criteria struct {
+	Type   string   `json:"type"`
+	Game   string   `json:"game,omitempty"`
+	Stat   pet.Stat `json:"stat,omitempty"`
+	Target int      `json:"target"`
+}
+
+// Source returns where the criteria's value comes from
+func (c Criteria) Source() Source {
+	return criteriaSources[c.Type]
+}
+
+// Definition describes one achievement
+type Definition struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Criteria    Criteria `json:"criteria"`
+}
+
+// Stats is a snapshot of everything criteria are evaluated against.
+// Per-game maps are keyed by catalogue game id.
+type Stats struct {
+	HabitCompletions int
+	HabitStreak      int
+	GamePlays        map[string]int
+	BestScores       map[string]int
+	MultiplayerWins  map[string]int
+	PetLevel         int
+	PetStats         map[pet.Stat]int
+}
+
+// Value returns the user's current value for the criteria
+func (c Criteria) Value(s *Stats) int {
+	switch c.Type {
+	case HabitCompletions:
+		return s.HabitCompletions
+	case HabitStreak:
+		return s.HabitStreak
+	case GamePlays:
+		return sumOrGame(s.GamePlays, c.Game)
+	case BestScore:
+		return s.BestScores[c.Game]
+	case MultiplayerWins:
+		return sumOrGame(s.MultiplayerWins, c.Game)
+	case PetLevel:
+		return s.PetLevel
+	case PetStat:
+		return s.PetStats[c.Stat]
+	}
+	return 0
+}
+
+// Progress returns the value towards the target, capped at the target
+func (c Criteria) Progress(s *Stats) int {
+	if v := c.Value(s); v < c.Target {
+		return v
+	}
+	return c.Target
+}
+
+// Met reports whether the criteria is satisfied
+func (c Criteria) Met(s *Stats) bool {
+	return c.Value(s) >= c.Target
+}
+
+func sumOrGame(m map[string]int, game string) int {
+	if game != "" {
+		return m[game]
+	}
+	total := 0
+	for _, v := range m {
+		total += v
+	}
+	return total
+}
+
+// Registry holds the loaded achievement definitions
+type Registry struct {
+	byID  map[string]*Definition
+	order []*Definition
+}
+
+// Parse parses and validates a JSON array of achievement definitions
+func Parse(data []byte) (*Registry, error) {
+	var list []*Definition
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("parse achievements: %w", err)
+	}
+
+	reg := &Registry{byID: make(map[string]*Definition, len(list))}
+	for _, d := range list {
+		if d.ID == "" {
+			return nil, fmt.Errorf("achievement without id")
+		}
+		if _, dup := reg.byID[d.ID]; dup {
+			return nil, fmt.Errorf("duplicate achievement %q", d.ID)
+		}
+		c := d.Criteria
+		if _, ok := criteriaSources[c.Type]; !ok {
+			return nil, fmt.Errorf("achievement %q: unknown criteria type %q", d.ID, c.Type)
+		}
+		if c.Target <= 0 {
+			return nil, fmt.Errorf("achievement %q: target must be positive", d.ID)
+		}
+		if c.Type == BestScore && c.Game == "" {
+			return nil, fmt.Errorf("achievement %q: %s needs a game", d.ID, BestScore)
+		}
+		if c.Type == PetStat && c.Stat == "" {
+			return nil, fmt.Errorf("achievement %q: %s needs a stat", d.ID, PetStat)
+		}
+		reg.byID[d.ID] = d
+		reg.order = append(reg.order, d)
+	}
+	return reg, nil
+}
+
+// LoadFile reads achievement definitions from path
+func LoadFile(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// All returns the achievements in definition order
+func (r *Registry) All() []*Definition {
+	return r.order
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   *Registry
+)
+
+func init() {
+	reg, err := Parse(embeddedDefinitions)
+	if err != nil {
+		panic(err)
+	}
+	registry = reg
+}
+
+// Use replaces the achievement definitions used by the package
+func Use(reg *Registry) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = reg
+}
+
+// Lookup returns the achievement with the given id
+func Lookup(id string) (*Definition, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	d, ok := registry.byID[id]
+	return d, ok
+}
+
+// All returns every loaded achievement definition
+func All() []*Definition {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return registry.All()
+}
+
+// Streak returns the number of consecutive days ending today or yesterday
+// found in days, which must be distinct UTC dates sorted newest first
+func Streak(days []time.Time, today time.Time) int {
+	today = today.UTC().Truncate(24 * time.Hour)
+	if len(days) == 0 {
+		return 0
+	}
+	expect := today
+	if days[0].Before(today) {
+		// Today's habit may still be pending; a streak ending yesterday is kept
+		expect = today.AddDate(0, 0, -1)
+	}
+	streak := 0
+	for _, d := range days {
+		if !d.UTC().Truncate(24 * time.Hour).Equal(expect) {
+			break
+		}
+		streak++
+		expect = expect.AddDate(0, 0, -1)
+	}
+	return streak
+}
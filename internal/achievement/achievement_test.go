@@ -0,0 +1,51 @@
+package achievement
+
+import (
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func days(ss ...string) []time.Time {
+	out := make([]time.Time, len(ss))
+	for i, s := range ss {
+		out[i] = day(s)
+	}
+	return out
+}
+
+func TestStreak(t *testing.T) {
+	// Late in the UTC day, so truncation to the date matters
+	today := time.Date(2026, 10, 18, 23, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		days  []time.Time
+		today time.Time
+		want  int
+	}{
+		{"no completions", nil, today, 0},
+		{"today only", days("2026-10-18"), today, 1},
+		{"yesterday only", days("2026-10-17"), today, 1},
+		{"ending today", days("2026-10-18", "2026-10-17", "2026-10-16"), today, 3},
+		{"ending yesterday", days("2026-10-17", "2026-10-16", "2026-10-15"), today, 3},
+		{"last completion two days ago", days("2026-10-16", "2026-10-15"), today, 0},
+		{"gap breaks the streak", days("2026-10-18", "2026-10-17", "2026-10-15", "2026-10-14"), today, 2},
+		{"across a month boundary", days("2026-10-02", "2026-10-01", "2026-09-30"), time.Date(2026, 10, 2, 8, 0, 0, 0, time.UTC), 3},
+		{"today given in another zone", days("2026-10-18", "2026-10-17"), time.Date(2026, 10, 19, 1, 0, 0, 0, time.FixedZone("CEST", 2*60*60)), 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Streak(tt.days, tt.today); got != tt.want {
+				t.Errorf("Streak() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,12 +1,25 @@
 package app
 
-import "os"
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"fsd-backend/internal/energy"
+)
 
 type Config struct {
-	Port          string
-	AllowedOrigin string
-	JWTSecret     string
-	DatabaseURL   string
+	Port             string
+	AllowedOrigin    string
+	JWTSecret        string
+	DatabaseURL      string
+	SpeciesFile      string
+	ItemsFile        string
+	GamesFile        string
+	AchievementsFile string
+	QuestsFile       string
+	EnergyRegen      energy.Regen
 }
 
 func LoadConfig() Config {
@@ -17,11 +30,54 @@ func LoadConfig() Config {
 	secret := os.Getenv("JWT_SECRET")
 	if secret == "" { secret = "dev-secret-change-me" }
 	dbURL := os.Getenv("DATABASE_URL")
+	speciesFile := os.Getenv("PET_SPECIES_FILE")
+	itemsFile := os.Getenv("PET_ITEMS_FILE")
+	gamesFile := os.Getenv("GAME_CATALOG_FILE")
+	achievementsFile := os.Getenv("ACHIEVEMENTS_FILE")
+	questsFile := os.Getenv("QUESTS_FILE")
+
+	regen := energy.DefaultRegen
+	regen.Points = envInt("ENERGY_REGEN_POINTS", regen.Points)
+	regen.Every = envDuration("ENERGY_REGEN_INTERVAL", regen.Every)
+	regen.Cap = envInt("ENERGY_REGEN_CAP", regen.Cap)
 
 	return Config{
-		Port:          port,
-		AllowedOrigin: origin,
-		JWTSecret:     secret,
-		DatabaseURL:   dbURL,
+		Port:             port,
+		AllowedOrigin:    origin,
+		JWTSecret:        secret,
+		DatabaseURL:      dbURL,
+		SpeciesFile:      speciesFile,
+		ItemsFile:        itemsFile,
+		GamesFile:        gamesFile,
+		AchievementsFile: achievementsFile,
+		QuestsFile:       questsFile,
+		EnergyRegen:      regen,
+	}
+}
+
+// envInt reads an integer environment variable, returning def if it is unset.
+// It panics on a value that does not parse so a typo can't silently become 0.
+func envInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		panic(fmt.Sprintf("invalid %s: %v", key, err))
+	}
+	return n
+}
+
+// envDuration is envInt for durations such as "6m"
+func envDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		panic(fmt.Sprintf("invalid %s: %v", key, err))
 	}
+	return d
 }
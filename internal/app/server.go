@@ -6,9 +6,14 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"fsd-backend/internal/achievement"
 	"fsd-backend/internal/auth"
 	"fsd-backend/internal/db"
+	"fsd-backend/internal/energy"
+	"fsd-backend/internal/game"
 	"fsd-backend/internal/middleware"
+	"fsd-backend/internal/pet"
+	"fsd-backend/internal/quest"
 	"fsd-backend/internal/routers"
 )
 
@@ -24,12 +29,39 @@ func NewServer(cfg Config) *gin.Engine {
 		7*24*time.Hour,
 	)
 
+	if cfg.SpeciesFile != "" {
+		species, err := pet.LoadSpeciesFile(cfg.SpeciesFile)
+		if err != nil { panic(err) }
+		pet.UseSpecies(species)
+	}
+	if cfg.ItemsFile != "" {
+		items, err := pet.LoadItemsFile(cfg.ItemsFile)
+		if err != nil { panic(err) }
+		pet.UseItems(items)
+	}
+	if cfg.GamesFile != "" {
+		games, err := game.LoadCatalogFile(cfg.GamesFile)
+		if err != nil { panic(err) }
+		game.UseCatalog(games)
+	}
+	if cfg.AchievementsFile != "" {
+		achievements, err := achievement.LoadFile(cfg.AchievementsFile)
+		if err != nil { panic(err) }
+		achievement.Use(achievements)
+	}
+	if cfg.QuestsFile != "" {
+		quests, err := quest.LoadFile(cfg.QuestsFile)
+		if err != nil { panic(err) }
+		quest.Use(quests)
+	}
+	if err := energy.UseRegen(cfg.EnergyRegen); err != nil { panic(err) }
+
 	pool, err := db.Connect(context.Background(), cfg.DatabaseURL)
 	if err != nil { panic(err) }
 
 	routers.RegisterSystemRoutes(r)
 	routers.RegisterAPIV1(r, cfg, signer, pool)
-	routers.RegisterWS(r, cfg, signer)
+	routers.RegisterWS(r, cfg, signer, pool)
 
 	return r
 }
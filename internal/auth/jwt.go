@@ -12,6 +12,16 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
+// GameSessionClaims identify a server-tracked minigame session
+type GameSessionClaims struct {
+	UserID    string `json:"uid"`
+	SessionID string `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// gameSessionSubject marks game session tokens so they can't be used as access tokens
+const gameSessionSubject = "game_session"
+
 type Signer struct {
 	secret     []byte
 	accessTTL  time.Duration
@@ -54,8 +64,37 @@ func (s *Signer) Parse(tokenStr string) (*Claims, error) {
 	if err != nil {
 		return nil, err
 	}
-	if c, ok := tok.Claims.(*Claims); ok && tok.Valid {
+	if c, ok := tok.Claims.(*Claims); ok && tok.Valid && c.Subject != gameSessionSubject {
 		return c, nil
 	}
 	return nil, errors.New("invalid token")
 }
+
+// SignGameSession signs a session id so clients can't finish sessions they didn't start
+func (s *Signer) SignGameSession(userID, sessionID string, expiresAt time.Time) (string, error) {
+	claims := GameSessionClaims{
+		UserID:    userID,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   gameSessionSubject,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return t.SignedString(s.secret)
+}
+
+// ParseGameSession validates a token from SignGameSession
+func (s *Signer) ParseGameSession(tokenStr string) (*GameSessionClaims, error) {
+	tok, err := jwt.ParseWithClaims(tokenStr, &GameSessionClaims{}, func(t *jwt.Token) (interface{}, error) {
+		return s.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}), jwt.WithSubject(gameSessionSubject))
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := tok.Claims.(*GameSessionClaims); ok && tok.Valid && c.SessionID != "" {
+		return c, nil
+	}
+	return nil, errors.New("invalid game session")
+}
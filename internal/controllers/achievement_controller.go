@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"net/http"
+
+	"fsd-backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AchievementController struct {
+	tracker *progressTracker
+}
+
+func NewAchievementController(db *pgxpool.Pool) *AchievementController {
+	return &AchievementController{tracker: newProgressTracker(db)}
+}
+
+// GET /achievements - List every achievement with the user's progress, locked or unlocked.
+// Achievements met since the last check are unlocked first and also returned in "newly_unlocked".
+func (ctl *AchievementController) List(c *gin.Context) {
+	userID := middleware.UserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	statuses, unlocked, err := ctl.tracker.evaluate(c, userID, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch achievements"})
+		return
+	}
+	if statuses == nil {
+		statuses = []achievementStatus{}
+	}
+	if unlocked == nil {
+		unlocked = []achievementStatus{}
+	}
+	c.JSON(http.StatusOK, gin.H{"data": statuses, "newly_unlocked": unlocked})
+}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"fsd-backend/internal/db"
+	"fsd-backend/internal/middleware"
+	"fsd-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AdminController serves manual corrections. Routes must be behind middleware.RequireAdmin.
+type AdminController struct {
+	db         *pgxpool.Pool
+	energyRepo *repository.EnergyRepo
+}
+
+func NewAdminController(db *pgxpool.Pool) *AdminController {
+	return &AdminController{
+		db:         db,
+		energyRepo: repository.NewEnergyRepo(db),
+	}
+}
+
+type adminEnergyReq struct {
+	Delta int    `json:"delta" binding:"required"`
+	Note  string `json:"note" binding:"required"`
+}
+
+// POST /admin/users/:id/energy - Correct a user's energy by delta.
+// The change is written to their ledger with the note and the admin's id.
+func (ctl *AdminController) AdjustEnergy(c *gin.Context) {
+	actorID := middleware.UserID(c)
+	userID := c.Param("id")
+	if _, err := uuid.Parse(userID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req adminEnergyReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var energy int
+	err := db.WithTxnRetry(c, ctl.db, func(tx pgx.Tx) error {
+		var err error
+		energy, err = ctl.energyRepo.AdminAdjustTx(c, tx, userID, req.Delta, req.Note, actorID)
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to adjust energy"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"user_id": userID, "energy": energy})
+}
+
+// GET /admin/users/:id/energy/history - Audit a user's energy ledger
+func (ctl *AdminController) GetEnergyHistory(c *gin.Context) {
+	userID := c.Param("id")
+	if _, err := uuid.Parse(userID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	energyHistory(c, ctl.energyRepo, userID)
+}
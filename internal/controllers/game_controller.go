@@ -1,28 +1,53 @@
 package controllers
 
 import (
-	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strings"
+	"time"
 
+	"fsd-backend/internal/auth"
+	"fsd-backend/internal/db"
+	"fsd-backend/internal/game"
 	"fsd-backend/internal/middleware"
+	"fsd-backend/internal/quest"
 	"fsd-backend/internal/repository"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type GameController struct {
-	repo     *repository.GameRepo
-	userRepo *repository.UserRepo
-	petRepo  *repository.PetRepo
+	db          *pgxpool.Pool
+	signer      *auth.Signer
+	repo        *repository.GameRepo
+	energyRepo  *repository.EnergyRepo
+	petRepo     *repository.PetRepo
+	sessionRepo *repository.GameSessionRepo
+	playRepo    *repository.GamePlayRepo
+	boardRepo   *repository.LeaderboardRepo
+	ratingRepo  *repository.RatingRepo
+	rewards     *gameRewards
+	tracker     *progressTracker
 }
 
-func NewGameController(db *pgxpool.Pool) *GameController {
+func NewGameController(db *pgxpool.Pool, signer *auth.Signer) *GameController {
 	return &GameController{
-		repo:     repository.NewGameRepo(db),
-		userRepo: repository.NewUserRepo(db),
-		petRepo:  repository.NewPetRepo(db),
+		db:          db,
+		signer:      signer,
+		repo:        repository.NewGameRepo(db),
+		energyRepo:  repository.NewEnergyRepo(db),
+		petRepo:     repository.NewPetRepo(db),
+		sessionRepo: repository.NewGameSessionRepo(db),
+		playRepo:    repository.NewGamePlayRepo(db),
+		boardRepo:   repository.NewLeaderboardRepo(db),
+		ratingRepo:  repository.NewRatingRepo(db),
+		rewards:     newGameRewards(db),
+		tracker:     newProgressTracker(db),
 	}
 }
 
@@ -53,19 +78,22 @@ func (g *GameController) GetUserData(c *gin.Context) {
 	}
 
 	// Get energy
-	energy, err := g.userRepo.GetEnergy(c.Request.Context(), userID)
+	status, err := g.energyRepo.GetStatus(c.Request.Context(), userID)
 	if err != nil {
-		energy = 30 // Default if error
+		status.Energy = 30 // Default if error
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"user_id":     userID,
-		"high_scores": highScores,
-		"energy":      energy,
+		"user_id":       userID,
+		"high_scores":   highScores,
+		"energy":        status.Energy,
+		"next_point_at": status.NextPointAt,
+		"full_at":       status.FullAt,
 	})
 }
 
-// POST /game/save - Save game data (e.g., high score) and increase mood
+// POST /game/save - Finish a game session and save its score (high score, mood, coins).
+// Same as POST /game/sessions/:id/finish with the session id in the body.
 func (g *GameController) SaveGameData(c *gin.Context) {
 	userID := middleware.UserID(c)
 	if userID == "" {
@@ -74,8 +102,9 @@ func (g *GameController) SaveGameData(c *gin.Context) {
 	}
 
 	var req struct {
-		GameType string `json:"game_type" binding:"required"`
-		Score    int    `json:"score" binding:"required"`
+		SessionID string `json:"session_id" binding:"required"`
+		GameType  string `json:"game_type" binding:"required"`
+		Score     *int   `json:"score" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -83,148 +112,402 @@ func (g *GameController) SaveGameData(c *gin.Context) {
 		return
 	}
 
-	// Increase mood FIRST - this happens for EVERY game, regardless of high score
-	// Jump Rope: adds score to mood
-	// Sunny Says: adds 2x score to mood
-	moodIncrease := req.Score
-	if req.GameType == "Sunny Says" {
-		moodIncrease = req.Score * 2
+	g.finishSession(c, userID, req.SessionID, req.GameType, *req.Score)
+}
+
+// POST /game/sessions - Start a game: deducts the energy cost and returns a signed session id
+func (g *GameController) StartSession(c *gin.Context) {
+	userID := middleware.UserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
 	}
 
-	if err := g.petRepo.AddMood(c.Request.Context(), userID, moodIncrease); err != nil {
-		// Log error but don't fail the request
-		// Mood update failure shouldn't prevent score saving
-		log.Printf("ERROR: Failed to increase mood for user %s: %v", userID, err)
+	var req struct {
+		GameType string `json:"game_type" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	def, ok := lookupEnabledGame(c, req.GameType)
+	if !ok {
+		return
 	}
 
-	// Upsert high score (only updates if new score is higher)
-	// This is separate from mood increase - mood increases for every game
-	game, err := g.repo.UpsertHighScore(context.Background(), userID, req.GameType, req.Score)
+	var session *repository.GameSession
+	var energy int
+	err := db.WithTxnRetry(c, g.db, func(tx pgx.Tx) error {
+		var err error
+		if session, err = g.sessionRepo.CreateTx(c, tx, userID, def.ID, def.SessionTTL()); err != nil {
+			return err
+		}
+		energy, err = g.energyRepo.SpendTx(c, tx, userID, def.EnergyCost, repository.EnergyReasonGameSession, session.ID)
+		return err
+	})
+	if errors.Is(err, repository.ErrInsufficientEnergy) {
+		current, _ := g.energyRepo.Get(c, userID)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":           "Insufficient energy",
+			"current_energy":  current,
+			"required_energy": def.EnergyCost,
+		})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save game data"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start game session"})
 		return
 	}
 
-	// Extract high score from response
-	highScore := 0
-	if score, ok := game.Attrs["high_score"].(float64); ok {
-		highScore = int(score)
-	} else if score, ok := game.Attrs["high_score"].(int); ok {
-		highScore = score
+	token, err := g.signer.SignGameSession(userID, session.ID, session.ExpiresAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start game session"})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":    "Game data saved successfully",
-		"user_id":    userID,
-		"game_type":  req.GameType,
-		"score":      req.Score,
-		"high_score": highScore,
+	c.JSON(http.StatusCreated, gin.H{
+		"session_id":  token,
+		"game_type":   def.Title,
+		"started_at":  session.StartedAt,
+		"expires_at":  session.ExpiresAt,
+		"energy_cost": def.EnergyCost,
+		"energy":      energy,
 	})
 }
 
-// POST /game/check-energy - Check if user has enough energy to play a game
-func (g *GameController) CheckEnergy(c *gin.Context) {
+// POST /game/sessions/:id/finish - Submit the score of an open session.
+// Rewards are applied exactly once, and only if the score is plausible for the time played.
+func (g *GameController) FinishSession(c *gin.Context) {
 	userID := middleware.UserID(c)
 	if userID == "" {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
 
 	var req struct {
-		GameType string `json:"game_type" binding:"required"`
+		Score *int `json:"score" binding:"required"`
 	}
-
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Get current energy
-	energy, err := g.userRepo.GetEnergy(c.Request.Context(), userID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch energy"})
+	g.finishSession(c, userID, c.Param("id"), "", *req.Score)
+}
+
+// finishSession closes the session identified by token and applies its rewards.
+// If gameType is set it must match the session's game.
+func (g *GameController) finishSession(c *gin.Context, userID, token, gameType string, score int) {
+	claims, err := g.signer.ParseGameSession(token)
+	if err != nil || claims.UserID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Game session not found"})
 		return
 	}
 
-	// Determine energy cost
-	var requiredEnergy int
-	switch req.GameType {
-	case "Jump Rope":
-		requiredEnergy = 10
-	case "Sunny Says":
-		requiredEnergy = 15
-	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid game type"})
+	errWrongGame := errors.New("game type does not match session")
+	var result *gameResult
+	var gameID string
+	rejected := false
+	err = db.WithTxnRetry(c, g.db, func(tx pgx.Tx) error {
+		rejected = false
+		session, err := g.sessionRepo.LockOpenTx(c, tx, claims.SessionID, userID)
+		if err != nil {
+			return err
+		}
+		def, ok := game.LookupGame(session.GameID)
+		if !ok {
+			return fmt.Errorf("game %q missing from catalog", session.GameID)
+		}
+		if gameType != "" {
+			if other, ok := game.LookupGame(gameType); !ok || other.ID != def.ID {
+				return errWrongGame
+			}
+		}
+
+		if !def.PlausibleScore(score, time.Since(session.StartedAt)) {
+			// Close the session without rewards so the score can't be retried lower
+			rejected = true
+			_, err := g.sessionRepo.FinishTx(c, tx, session.ID, repository.GameSessionRejected, score)
+			return err
+		}
+
+		finished, err := g.sessionRepo.FinishTx(c, tx, session.ID, repository.GameSessionFinished, score)
+		if err != nil {
+			return err
+		}
+		sessionID := finished.ID
+		gameID = def.ID
+		err = g.playRepo.RecordTx(c, tx, &repository.GamePlay{
+			UserID:     userID,
+			GameID:     def.ID,
+			Score:      score,
+			DurationMs: int(finished.FinishedAt.Sub(finished.StartedAt).Milliseconds()),
+			Mode:       repository.PlayModeSolo,
+			SessionID:  &sessionID,
+			StartedAt:  finished.StartedAt,
+			EndedAt:    *finished.FinishedAt,
+		})
+		if err != nil {
+			return err
+		}
+		result, err = g.rewards.applyTx(c, tx, userID, def, score, true)
+		return err
+	})
+	switch {
+	case errors.Is(err, repository.ErrSessionNotOpen):
+		c.JSON(http.StatusConflict, gin.H{"error": "Game session is already finished or expired"})
+		return
+	case errors.Is(err, errWrongGame):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		log.Printf("ERROR: Failed to finish game session for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save game data"})
+		return
+	case rejected:
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Score rejected as implausible"})
 		return
 	}
 
-	hasEnough := energy >= requiredEnergy
-
 	c.JSON(http.StatusOK, gin.H{
-		"has_enough_energy": hasEnough,
-		"current_energy":    energy,
-		"required_energy":   requiredEnergy,
+		"message":       "Game data saved successfully",
+		"user_id":       userID,
+		"game_type":     result.GameType,
+		"score":         result.Score,
+		"high_score":    result.HighScore,
+		"mood_increase": result.MoodIncrease,
+		"xp_earned":     result.XPEarned,
+		"coins_earned":  result.CoinsEarned,
+		"pet":           result.Pet,
+
+		"achievements_unlocked": g.tracker.afterEvent(c, userID, quest.Event{
+			Kind: quest.EventGamePlayed, GameID: gameID, Score: result.Score,
+		}),
 	})
 }
 
-// POST /game/deduct-energy - Deduct energy before starting a game
-func (g *GameController) DeductEnergy(c *gin.Context) {
+// GET /game/history - Get the user's individual plays, newest first.
+// Query: game (id or title, optional), limit (1-100, default 20), cursor (next_cursor of the previous page)
+func (g *GameController) GetHistory(c *gin.Context) {
 	userID := middleware.UserID(c)
 	if userID == "" {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
 
-	var req struct {
-		GameType string `json:"game_type" binding:"required"`
+	gameID := ""
+	if gameType := c.Query("game"); gameType != "" {
+		def, ok := game.LookupGame(gameType)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid game type"})
+			return
+		}
+		gameID = def.ID
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	limit, ok := intQuery(c, "limit", 20, 1, 100)
+	if !ok {
 		return
 	}
 
-	// Get current energy
-	currentEnergy, err := g.userRepo.GetEnergy(c.Request.Context(), userID)
+	var afterEndedAt *time.Time
+	var afterID string
+	if cursor := c.Query("cursor"); cursor != "" {
+		t, id, err := decodeCursor(cursor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		afterEndedAt, afterID = &t, id
+	}
+
+	plays, err := g.playRepo.History(c, userID, gameID, afterEndedAt, afterID, limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch energy"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch game history"})
+		return
+	}
+
+	var next *string
+	if len(plays) == limit {
+		last := plays[len(plays)-1]
+		cursor := encodeCursor(last.EndedAt, last.ID)
+		next = &cursor
+	}
+	c.JSON(http.StatusOK, gin.H{"data": plays, "next_cursor": next})
+}
+
+// GET /game/ratings/:title - Get the user's matchmaking rating in a multiplayer game
+func (g *GameController) GetRating(c *gin.Context) {
+	userID := middleware.UserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	def, ok := game.LookupGame(c.Param("title"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "game not found"})
+		return
+	}
+	rating, err := g.ratingRepo.Get(c, userID, def.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rating"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": rating})
+}
+
+// GET /game/stats - Get per-game aggregates of the user's plays (overall and last 7 days)
+func (g *GameController) GetStats(c *gin.Context) {
+	userID := middleware.UserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	stats, err := g.playRepo.Stats(c, userID, time.Now().AddDate(0, 0, -7))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch game stats"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": stats})
+}
+
+// GET /game/leaderboards/:title - Rank players by their best score in a game.
+// Query: window (all, weekly or daily; default all), limit (1-100, default 10),
+// user_ids (comma-separated; restricts the board to these users plus the caller),
+// neighbours (0-10, default 2; entries shown either side of the caller).
+// Weekly boards start on Monday and daily boards at midnight, both in UTC.
+func (g *GameController) GetLeaderboard(c *gin.Context) {
+	userID := middleware.UserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	def, ok := game.LookupGame(c.Param("title"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "game not found"})
 		return
 	}
 
-	// Determine energy cost
-	var energyCost int
-	switch req.GameType {
-	case "Jump Rope":
-		energyCost = 10
-	case "Sunny Says":
-		energyCost = 15
+	q := repository.LeaderboardQuery{
+		Title:  def.Title,
+		GameID: def.ID,
+		Window: c.DefaultQuery("window", repository.LeaderboardAllTime),
+	}
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch q.Window {
+	case repository.LeaderboardAllTime:
+	case repository.LeaderboardDaily:
+		q.Since, q.Until = today, today.AddDate(0, 0, 1)
+	case repository.LeaderboardWeekly:
+		// time.Weekday starts on Sunday; shift so the week starts on Monday
+		monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+		q.Since, q.Until = monday, monday.AddDate(0, 0, 7)
 	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid game type"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "window must be all, weekly or daily"})
 		return
 	}
 
-	// Check if user has enough energy
-	if currentEnergy < energyCost {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":           "Insufficient energy",
-			"current_energy":  currentEnergy,
-			"required_energy": energyCost,
-		})
+	limit, ok := intQuery(c, "limit", 10, 1, 100)
+	if !ok {
 		return
 	}
+	neighbours, ok := intQuery(c, "neighbours", 2, 0, 10)
+	if !ok {
+		return
+	}
+
+	if raw := c.Query("user_ids"); raw != "" {
+		ids := strings.Split(raw, ",")
+		if len(ids) > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "at most 100 user_ids"})
+			return
+		}
+		q.UserIDs = []string{userID}
+		for _, id := range ids {
+			id = strings.TrimSpace(id)
+			if _, err := uuid.Parse(id); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id " + id})
+				return
+			}
+			q.UserIDs = append(q.UserIDs, id)
+		}
+	}
 
-	// Deduct energy
-	newEnergy := currentEnergy - energyCost
-	if err := g.userRepo.UpdateEnergy(c.Request.Context(), userID, newEnergy); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update energy"})
+	top, total, err := g.boardRepo.Top(c, q, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leaderboard"})
 		return
 	}
+	around, err := g.boardRepo.Around(c, q, userID, neighbours)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leaderboard"})
+		return
+	}
+
+	var me *repository.LeaderboardEntry
+	for i := range around {
+		if around[i].UserID == userID {
+			me = &around[i]
+			break
+		}
+	}
+
+	resp := gin.H{
+		"game_type":  def.Title,
+		"window":     q.Window,
+		"total":      total,
+		"data":       top,
+		"me":         me,
+		"neighbours": around,
+	}
+	if q.Window != repository.LeaderboardAllTime {
+		resp["starts_at"] = q.Since
+		resp["ends_at"] = q.Until
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// POST /game/check-energy - Check if user has enough energy to play a game
+func (g *GameController) CheckEnergy(c *gin.Context) {
+	userID := middleware.UserID(c)
+	if userID == "" {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	var req struct {
+		GameType string `json:"game_type" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	def, ok := lookupEnabledGame(c, req.GameType)
+	if !ok {
+		return
+	}
+	requiredEnergy := def.EnergyCost
+
+	// Get current energy
+	energy, err := g.energyRepo.Get(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch energy"})
+		return
+	}
+
+	hasEnough := energy >= requiredEnergy
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":         "Energy deducted successfully",
-		"previous_energy": currentEnergy,
-		"new_energy":      newEnergy,
-		"energy_cost":     energyCost,
+		"has_enough_energy": hasEnough,
+		"current_energy":    energy,
+		"required_energy":   requiredEnergy,
 	})
 }
 
@@ -244,3 +527,29 @@ func (g *GameController) GetMood(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"mood": mood})
 }
+
+// GET /game/catalog - List the available minigames with their costs and rewards
+func (g *GameController) GetCatalog(c *gin.Context) {
+	games := []*game.Definition{}
+	for _, def := range game.AllGames() {
+		if def.Enabled {
+			games = append(games, def)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"data": games})
+}
+
+// lookupEnabledGame resolves a game_type (catalogue id or title) to an enabled game.
+// On failure the error response has already been written.
+func lookupEnabledGame(c *gin.Context, gameType string) (*game.Definition, bool) {
+	def, ok := game.LookupGame(gameType)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid game type"})
+		return nil, false
+	}
+	if !def.Enabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Game is disabled"})
+		return nil, false
+	}
+	return def, true
+}
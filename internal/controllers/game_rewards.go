@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"fsd-backend/internal/game"
+	"fsd-backend/internal/pet"
+	"fsd-backend/internal/repository"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// gameRewards applies the rewards for a verified minigame result:
+// pet mood and XP, coins and the high score
+type gameRewards struct {
+	gameRepo      *repository.GameRepo
+	petRepo       *repository.PetRepo
+	inventoryRepo *repository.InventoryRepo
+}
+
+func newGameRewards(db *pgxpool.Pool) *gameRewards {
+	return &gameRewards{
+		gameRepo:      repository.NewGameRepo(db),
+		petRepo:       repository.NewPetRepo(db),
+		inventoryRepo: repository.NewInventoryRepo(db),
+	}
+}
+
+type gameResult struct {
+	GameType     string     `json:"game_type"`
+	Score        int        `json:"score"`
+	HighScore    int        `json:"high_score"`
+	MoodIncrease int        `json:"mood_increase"`
+	XPEarned     int        `json:"xp_earned"`
+	CoinsEarned  int        `json:"coins_earned"`
+	Pet          *pet.State `json:"pet"`
+}
+
+// applyTx grants everything score earns in def inside tx. Coins are only paid
+// when the player spent the game's energy cost on it (paid), so free games
+// can't be farmed for them.
+func (r *gameRewards) applyTx(ctx context.Context, tx pgx.Tx, userID string, def *game.Definition, score int, paid bool) (*gameResult, error) {
+	res := &gameResult{
+		GameType:     def.Title,
+		Score:        score,
+		MoodIncrease: def.Mood.Apply(score),
+		XPEarned:     def.XP(score),
+	}
+	if paid {
+		res.CoinsEarned = def.Coins(score)
+	}
+
+	p, err := r.petRepo.LockByUserIDTx(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	_, res.Pet, err = r.petRepo.UpdateStateTx(ctx, tx, p.ID, func(s *pet.State) error {
+		s.Add(pet.StatHappiness, res.MoodIncrease, time.Now())
+		s.AddXP(res.XPEarned)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if res.CoinsEarned > 0 {
+		if _, err := r.inventoryRepo.AddCoinsTx(ctx, tx, userID, res.CoinsEarned); err != nil {
+			return nil, err
+		}
+	}
+
+	saved, err := r.gameRepo.UpsertHighScoreTx(ctx, tx, userID, def.Title, score)
+	if err != nil {
+		return nil, err
+	}
+	if hs, ok := saved.Attrs["high_score"].(float64); ok {
+		res.HighScore = int(hs)
+	}
+	return res, nil
+}
@@ -2,24 +2,42 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"time"
 
+	"fsd-backend/internal/db"
 	"fsd-backend/internal/middleware"
+	"fsd-backend/internal/quest"
 	"fsd-backend/internal/repository"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// Rewards for completing a habit (taken back when it is un-completed, which
+// fails if the user no longer has them)
+const (
+	habitCompletionEnergy = 5
+	habitCompletionCoins  = 10
+)
+
 type HabitController struct {
-	repo     *repository.HabitRepo
-	userRepo *repository.UserRepo
+	db            *pgxpool.Pool
+	repo          *repository.HabitRepo
+	energyRepo    *repository.EnergyRepo
+	inventoryRepo *repository.InventoryRepo
+	tracker       *progressTracker
 }
 
 func NewHabitController(db *pgxpool.Pool) *HabitController {
 	return &HabitController{
-		repo:     repository.NewHabitRepo(db),
-		userRepo: repository.NewUserRepo(db),
+		db:            db,
+		repo:          repository.NewHabitRepo(db),
+		energyRepo:    repository.NewEnergyRepo(db),
+		inventoryRepo: repository.NewInventoryRepo(db),
+		tracker:       newProgressTracker(db),
 	}
 }
 
@@ -130,43 +148,89 @@ func (ctl *HabitController) Update(c *gin.Context) {
 		return
 	}
 
-	// Check if done status is being changed
-	var energyChange int
-	if req.Done != nil {
-		wasDone := h.Done
-		isNowDone := *req.Done
-		if wasDone != isNowDone {
-			// Completing: +5, Uncompleting: -5
-			if isNowDone {
-				energyChange = +5
-			} else {
-				energyChange = -5
+	// Flip done, grant or take back the rewards and log the completion in one
+	// transaction, and only if this request actually changed the state
+	var updatedHabit *repository.Habit
+	var completed bool
+	var reopenedCompletion *time.Time // When the completion undone by reopening was made
+	err = db.WithTxnRetry(c, ctl.db, func(tx pgx.Tx) error {
+		completed, reopenedCompletion = false, nil
+		if req.Done != nil {
+			changed, err := ctl.repo.SetDoneTx(c, tx, id, userID, *req.Done)
+			if err != nil {
+				return err
+			}
+			switch {
+			case changed && *req.Done:
+				if err := ctl.completeTx(c, tx, userID, id); err != nil {
+					return err
+				}
+				completed = true
+			case changed:
+				if reopenedCompletion, err = ctl.reopenTx(c, tx, userID, id); err != nil {
+					return err
+				}
 			}
 		}
+		h, err := ctl.repo.UpdateTx(c, tx, id, req.Title, nil, req.Icons, req.Cadence)
+		updatedHabit = h
+		return err
+	})
+	if errors.Is(err, repository.ErrInsufficientEnergy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Insufficient energy to reopen habit"})
+		return
+	}
+	if errors.Is(err, repository.ErrInsufficientCoins) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Insufficient coins to reopen habit"})
+		return
 	}
-
-	// Update the habit
-	updatedHabit, err := ctl.repo.Update(c, id, req.Title, req.Done, req.Icons, req.Cadence)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Update energy if habit done status changed
-	if energyChange != 0 {
-		currentEnergy, err := ctl.userRepo.GetEnergy(c, userID)
-		if err == nil {
-			newEnergy := currentEnergy + energyChange
-			if newEnergy < 0 {
-				newEnergy = 0
-			} else if newEnergy > 100 {
-				newEnergy = 100
-			}
-			ctl.userRepo.UpdateEnergy(c, userID, newEnergy)
-		}
+	resp := gin.H{"data": updatedHabit}
+	if completed {
+		resp["achievements_unlocked"] = ctl.tracker.afterEvent(c, userID, quest.Event{Kind: quest.EventHabitCompleted})
+	}
+	if reopenedCompletion != nil {
+		// Reopening takes the completion back from the quests of its period; it never unlocks anything
+		ctl.tracker.afterEvent(c, userID, quest.Event{Kind: quest.EventHabitReopened, CompletedAt: *reopenedCompletion})
 	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// completeTx grants the completion rewards and logs the completion
+func (ctl *HabitController) completeTx(ctx context.Context, tx pgx.Tx, userID, habitID string) error {
+	if _, err := ctl.energyRepo.GrantTx(ctx, tx, userID, habitCompletionEnergy, repository.EnergyReasonHabitCompleted, habitID); err != nil {
+		return err
+	}
+	if _, err := ctl.inventoryRepo.AddCoinsTx(ctx, tx, userID, habitCompletionCoins); err != nil {
+		return err
+	}
+	return ctl.repo.AddCompletionTx(ctx, tx, userID, habitID, time.Now())
+}
 
-	c.JSON(http.StatusOK, gin.H{"data": updatedHabit})
+// reopenTx takes the completion rewards and the logged completion back, and
+// returns when that completion was made (nil if none was logged). It fails with
+// ErrInsufficientEnergy or ErrInsufficientCoins if the rewards were already spent.
+func (ctl *HabitController) reopenTx(ctx context.Context, tx pgx.Tx, userID, habitID string) (*time.Time, error) {
+	// Take back exactly what the completion granted, which is less than
+	// habitCompletionEnergy near the cap; flooring at zero would let a user spend
+	// the rewards, reopen and complete again for a fresh grant
+	granted, err := ctl.energyRepo.LastGrantTx(ctx, tx, userID, repository.EnergyReasonHabitCompleted, habitID)
+	if err != nil {
+		return nil, err
+	}
+	if granted > 0 {
+		if _, err := ctl.energyRepo.SpendTx(ctx, tx, userID, granted, repository.EnergyReasonHabitReopened, habitID); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := ctl.inventoryRepo.SpendCoinsTx(ctx, tx, userID, habitCompletionCoins); err != nil {
+		return nil, err
+	}
+	return ctl.repo.RemoveLatestCompletionTx(ctx, tx, userID, habitID)
 }
 
 // DELETE /habits/:id - Delete a habit by ID
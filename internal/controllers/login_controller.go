@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"fsd-backend/internal/db"
+	"fsd-backend/internal/login"
+	"fsd-backend/internal/middleware"
+	"fsd-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Calendar day statuses
+const (
+	loginDayClaimed   = "claimed"
+	loginDayClaimable = "claimable"
+	loginDayUnclaimed = "unclaimed" // Logged in but the reward was not claimed that day
+	loginDayUpcoming  = "upcoming"
+)
+
+// LoginController serves the daily login streak. Logins themselves are recorded
+// by middleware.RecordLogins on every authenticated request.
+type LoginController struct {
+	db            *pgxpool.Pool
+	loginRepo     *repository.LoginRepo
+	energyRepo    *repository.EnergyRepo
+	inventoryRepo *repository.InventoryRepo
+}
+
+func NewLoginController(db *pgxpool.Pool) *LoginController {
+	return &LoginController{
+		db:            db,
+		loginRepo:     repository.NewLoginRepo(db),
+		energyRepo:    repository.NewEnergyRepo(db),
+		inventoryRepo: repository.NewInventoryRepo(db),
+	}
+}
+
+type loginCalendarDay struct {
+	CycleDay int          `json:"cycle_day"`
+	Date     string       `json:"date"`
+	Reward   login.Reward `json:"reward"`
+	Status   string       `json:"status"`
+}
+
+// today records the user's login for their current day (in case the middleware
+// skipped it) and returns that day and when the next one starts
+func (ctl *LoginController) today(c *gin.Context, userID string) (day, next time.Time, err error) {
+	if _, err := ctl.loginRepo.RecordLogin(c, userID); err != nil {
+		return day, next, err
+	}
+	loc, err := ctl.loginRepo.Location(c, userID)
+	if err != nil {
+		return day, next, err
+	}
+	day, next = login.Day(time.Now(), loc)
+	return day, next, nil
+}
+
+// GET /users/me/daily-login - Get the login streak and the current 7-day reward cycle.
+// Each day of the cycle is claimed, claimable (today), unclaimed or upcoming.
+func (ctl *LoginController) GetCalendar(c *gin.Context) {
+	userID := middleware.UserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	day, next, err := ctl.today(c, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch daily login"})
+		return
+	}
+	logins, err := ctl.loginRepo.Range(c, userID, day.AddDate(0, 0, 1-login.CycleLength), day)
+	if err != nil || len(logins) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch daily login"})
+		return
+	}
+	byDate := make(map[string]repository.DailyLogin, len(logins))
+	for _, l := range logins {
+		byDate[l.Day.Format(time.DateOnly)] = l
+	}
+
+	todayKey := day.Format(time.DateOnly)
+	current := byDate[todayKey]
+	cycleDay := login.CycleDay(current.Streak)
+	cycleStart := day.AddDate(0, 0, 1-cycleDay)
+
+	days := make([]loginCalendarDay, 0, login.CycleLength)
+	for i := 0; i < login.CycleLength; i++ {
+		date := cycleStart.AddDate(0, 0, i).Format(time.DateOnly)
+		d := loginCalendarDay{CycleDay: i + 1, Date: date, Reward: login.Rewards[i], Status: loginDayUpcoming}
+		if l, ok := byDate[date]; ok && date <= todayKey {
+			switch {
+			case l.ClaimedAt != nil:
+				d.Status = loginDayClaimed
+			case date == todayKey:
+				d.Status = loginDayClaimable
+			default:
+				d.Status = loginDayUnclaimed
+			}
+		}
+		days = append(days, d)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"today":       todayKey,
+		"streak":      current.Streak,
+		"cycle_day":   cycleDay,
+		"claimed":     current.ClaimedAt != nil,
+		"next_day_at": next,
+		"days":        days,
+	})
+}
+
+// POST /users/me/daily-login/claim - Claim today's login reward (once per day in the user's time zone)
+func (ctl *LoginController) Claim(c *gin.Context) {
+	userID := middleware.UserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	day, _, err := ctl.today(c, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to claim daily login reward"})
+		return
+	}
+	dayKey := day.Format(time.DateOnly)
+
+	var claimed *repository.DailyLogin
+	var reward login.Reward
+	var energy, coins int
+	err = db.WithTxnRetry(c, ctl.db, func(tx pgx.Tx) error {
+		var err error
+		claimed, err = ctl.loginRepo.ClaimTx(c, tx, userID, day)
+		if err != nil {
+			return err
+		}
+		reward = login.RewardFor(claimed.Streak)
+		energy, err = ctl.energyRepo.GrantOnceTx(c, tx, userID, reward.Energy, repository.EnergyReasonDailyLogin, dayKey)
+		if err != nil {
+			return err
+		}
+		coins, err = ctl.inventoryRepo.AddCoinsTx(c, tx, userID, reward.Coins)
+		return err
+	})
+	switch {
+	case errors.Is(err, repository.ErrLoginClaimed), errors.Is(err, repository.ErrAlreadyGranted):
+		c.JSON(http.StatusConflict, gin.H{"error": "Daily login reward already claimed", "day": dayKey})
+		return
+	case err != nil:
+		log.Printf("ERROR: Failed to claim daily login reward for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to claim daily login reward"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"day":       dayKey,
+		"streak":    claimed.Streak,
+		"cycle_day": login.CycleDay(claimed.Streak),
+		"reward":    reward,
+		"energy":    energy,
+		"coins":     coins,
+	})
+}
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// intQuery parses an optional integer query parameter within [min, max].
+// On failure the error response has already been written.
+func intQuery(c *gin.Context, key string, def, min, max int) (int, bool) {
+	v := c.Query(key)
+	if v == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < min || n > max {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s must be between %d and %d", key, min, max)})
+		return 0, false
+	}
+	return n, true
+}
+
+// encodeCursor packs the sort key (timestamp and id) of the last row on a page into an opaque string
+func encodeCursor(t time.Time, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(t.UTC().Format(time.RFC3339Nano) + "|" + id))
+}
+
+// decodeCursor unpacks a cursor from encodeCursor, rejecting ids that are not UUIDs
+// so a tampered cursor is a bad request rather than a database error
+func decodeCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	ts, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return time.Time{}, "", errors.New("malformed cursor")
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return time.Time{}, "", err
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	return t, id, err
+}
@@ -0,0 +1,293 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"fsd-backend/internal/db"
+	"fsd-backend/internal/middleware"
+	"fsd-backend/internal/pet"
+	"fsd-backend/internal/quest"
+	"fsd-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PetController struct {
+	db            *pgxpool.Pool
+	repo          *repository.PetRepo
+	energyRepo    *repository.EnergyRepo
+	inventoryRepo *repository.InventoryRepo
+	tracker       *progressTracker
+}
+
+func NewPetController(db *pgxpool.Pool) *PetController {
+	return &PetController{
+		db:            db,
+		repo:          repository.NewPetRepo(db),
+		energyRepo:    repository.NewEnergyRepo(db),
+		inventoryRepo: repository.NewInventoryRepo(db),
+		tracker:       newProgressTracker(db),
+	}
+}
+
+// GET /pets/me - Get the authenticated user's pet (created with defaults if missing)
+func (ctl *PetController) GetMine(c *gin.Context) {
+	userID := middleware.UserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	p, state, err := ctl.repo.UpdateStateForUser(c, userID, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pet"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": p, "state": state, "appearance": p.Appearance()})
+}
+
+// GET /pets/:id/state - Get the pet's stats with passive decay applied
+func (ctl *PetController) GetState(c *gin.Context) {
+	userID := middleware.UserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	p, ok := ctl.ownedPet(c, userID)
+	if !ok {
+		return
+	}
+
+	p, state, err := ctl.repo.GetState(c, p.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pet state"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"pet_id": p.ID, "species": p.Species, "state": state, "appearance": p.Appearance()})
+}
+
+// GET /pets/:id/events - Get events (e.g. evolutions) the client has not shown yet.
+// Returned events are marked as seen.
+func (ctl *PetController) GetEvents(c *gin.Context) {
+	userID := middleware.UserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	p, ok := ctl.ownedPet(c, userID)
+	if !ok {
+		return
+	}
+
+	events, err := ctl.repo.PopEvents(c, p.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pet events"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": events})
+}
+
+// POST /pets/:id/{feed,pet,clean,sleep} - Perform a care action on the pet.
+// The action's energy cost is spent in the same transaction as the stat change.
+func (ctl *PetController) Care(action string) gin.HandlerFunc {
+	care, ok := pet.CareActions[action]
+	if !ok {
+		panic("unknown care action " + action)
+	}
+
+	return func(c *gin.Context) {
+		userID := middleware.UserID(c)
+		if userID == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+
+		p, ok := ctl.ownedPet(c, userID)
+		if !ok {
+			return
+		}
+
+		var state *pet.State
+		var energy int
+		err := db.WithTxnRetry(c, ctl.db, func(tx pgx.Tx) error {
+			var err error
+			_, state, err = ctl.repo.UpdateStateTx(c, tx, p.ID, func(s *pet.State) error {
+				return s.ApplyCare(care, time.Now())
+			})
+			if err != nil {
+				return err
+			}
+			if care.EnergyCost > 0 {
+				energy, err = ctl.energyRepo.SpendTx(c, tx, userID, care.EnergyCost, repository.EnergyReasonPetCare, p.ID)
+			}
+			return err
+		})
+
+		var cooldown *pet.CooldownError
+		switch {
+		case errors.As(err, &cooldown):
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":               "Action on cooldown",
+				"retry_after_seconds": int(cooldown.Remaining.Seconds()) + 1,
+			})
+			return
+		case errors.Is(err, pet.ErrAsleep):
+			c.JSON(http.StatusConflict, gin.H{"error": "Pet is asleep"})
+			return
+		case errors.Is(err, repository.ErrInsufficientEnergy):
+			current, _ := ctl.energyRepo.Get(c, userID)
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":           "Insufficient energy",
+				"current_energy":  current,
+				"required_energy": care.EnergyCost,
+			})
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update pet"})
+			return
+		}
+
+		if care.EnergyCost == 0 {
+			energy, _ = ctl.energyRepo.Get(c, userID)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"action":      care.ID,
+			"energy_cost": care.EnergyCost,
+			"energy":      energy,
+			"state":       state,
+
+			"achievements_unlocked": ctl.tracker.afterEvent(c, userID, quest.Event{Kind: quest.EventPetCare}),
+		})
+	}
+}
+
+type equipReq struct {
+	ItemID string `json:"item_id" binding:"required"`
+}
+
+// PUT /pets/:id/loadout/:slot - Equip an owned cosmetic item in a slot
+func (ctl *PetController) Equip(c *gin.Context) {
+	userID := middleware.UserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	slot := pet.Slot(c.Param("slot"))
+	if !pet.ValidSlot(slot) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown slot"})
+		return
+	}
+
+	var req equipReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	item, ok := pet.LookupItem(req.ItemID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "item not found"})
+		return
+	}
+
+	p, ok := ctl.ownedPet(c, userID)
+	if !ok {
+		return
+	}
+
+	err := db.WithTxnRetry(c, ctl.db, func(tx pgx.Tx) error {
+		qty, err := ctl.inventoryRepo.GetQuantityTx(c, tx, userID, item.ID)
+		if err != nil {
+			return err
+		}
+		if qty == 0 {
+			return repository.ErrItemNotOwned
+		}
+		p, err = ctl.repo.UpdateLoadoutTx(c, tx, p.ID, func(l pet.Loadout) error {
+			return l.Equip(slot, item)
+		})
+		return err
+	})
+	switch {
+	case errors.Is(err, pet.ErrWrongSlot):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Item does not fit this slot"})
+		return
+	case errors.Is(err, repository.ErrItemNotOwned):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Item not in inventory"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to equip item"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"appearance": p.Appearance()})
+}
+
+// DELETE /pets/:id/loadout/:slot - Remove whatever is equipped in a slot
+func (ctl *PetController) Unequip(c *gin.Context) {
+	userID := middleware.UserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	slot := pet.Slot(c.Param("slot"))
+	if !pet.ValidSlot(slot) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown slot"})
+		return
+	}
+
+	p, ok := ctl.ownedPet(c, userID)
+	if !ok {
+		return
+	}
+
+	err := db.WithTxnRetry(c, ctl.db, func(tx pgx.Tx) error {
+		var err error
+		p, err = ctl.repo.UpdateLoadoutTx(c, tx, p.ID, func(l pet.Loadout) error {
+			delete(l, slot)
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unequip item"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"appearance": p.Appearance()})
+}
+
+// GET /pets/care-actions - List care actions with their costs, cooldowns and effects
+func (ctl *PetController) ListCareActions(c *gin.Context) {
+	actions := make([]pet.CareAction, 0, len(pet.CareActions))
+	for _, id := range []string{pet.CareFeed, pet.CarePet, pet.CareClean, pet.CareSleep} {
+		actions = append(actions, pet.CareActions[id])
+	}
+	c.JSON(http.StatusOK, gin.H{"data": actions})
+}
+
+// GET /pets/species - List species definitions
+func (ctl *PetController) ListSpecies(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": pet.AllSpecies()})
+}
+
+// ownedPet loads the pet from the :id param and checks it belongs to userID.
+// On failure the error response has already been written.
+func (ctl *PetController) ownedPet(c *gin.Context, userID string) (*repository.Pet, bool) {
+	p, err := ctl.repo.GetByID(c, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "pet not found"})
+		return nil, false
+	}
+	if p.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return nil, false
+	}
+	return p, true
+}
@@ -0,0 +1,243 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"fsd-backend/internal/achievement"
+	"fsd-backend/internal/pet"
+	"fsd-backend/internal/quest"
+	"fsd-backend/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// streakLookbackDays bounds how many completion days are read to compute a streak
+const streakLookbackDays = 366
+
+// progressTracker advances quests and re-evaluates achievements after events
+// that can move them (habit completions, finished games, pet care) and records new unlocks
+type progressTracker struct {
+	habitRepo       *repository.HabitRepo
+	playRepo        *repository.GamePlayRepo
+	petRepo         *repository.PetRepo
+	achievementRepo *repository.AchievementRepo
+	questRepo       *repository.QuestRepo
+}
+
+func newProgressTracker(db *pgxpool.Pool) *progressTracker {
+	return &progressTracker{
+		habitRepo:       repository.NewHabitRepo(db),
+		playRepo:        repository.NewGamePlayRepo(db),
+		petRepo:         repository.NewPetRepo(db),
+		achievementRepo: repository.NewAchievementRepo(db),
+		questRepo:       repository.NewQuestRepo(db),
+	}
+}
+
+// achievementStatus is an achievement as shown to one user
+type achievementStatus struct {
+	*achievement.Definition
+	Progress   int        `json:"progress"`
+	Target     int        `json:"target"`
+	Unlocked   bool       `json:"unlocked"`
+	UnlockedAt *time.Time `json:"unlocked_at,omitempty"`
+}
+
+// afterEvent advances the user's quests with ev, evaluates their achievements and
+// returns the ones unlocked by the event. Failures are logged so they never fail
+// the request that triggered them.
+func (t *progressTracker) afterEvent(ctx context.Context, userID string, ev quest.Event) []achievementStatus {
+	if err := t.advanceQuests(ctx, userID, ev); err != nil {
+		log.Printf("ERROR: Failed to advance quests for user %s: %v", userID, err)
+	}
+	_, unlocked, err := t.evaluate(ctx, userID, false)
+	if err != nil {
+		log.Printf("ERROR: Failed to evaluate achievements for user %s: %v", userID, err)
+		return nil
+	}
+	return unlocked
+}
+
+// evaluate records every achievement the user now meets and returns the newly
+// unlocked ones. With all set it also returns the status of every achievement;
+// otherwise only the data needed by still-locked achievements is loaded.
+func (t *progressTracker) evaluate(ctx context.Context, userID string, all bool) (statuses, unlocked []achievementStatus, err error) {
+	have, err := t.achievementRepo.Unlocked(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defs := achievement.All()
+	needed := map[achievement.Source]bool{}
+	for _, d := range defs {
+		if _, ok := have[d.ID]; !ok || all {
+			needed[d.Criteria.Source()] = true
+		}
+	}
+	if len(needed) == 0 {
+		return nil, nil, nil
+	}
+	stats, err := t.stats(ctx, userID, needed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var met []string
+	for _, d := range defs {
+		if _, ok := have[d.ID]; !ok && d.Criteria.Met(stats) {
+			met = append(met, d.ID)
+		}
+	}
+	if len(met) > 0 {
+		added, err := t.achievementRepo.Unlock(ctx, userID, met)
+		if err != nil {
+			return nil, nil, err
+		}
+		for id, at := range added {
+			have[id] = at
+		}
+		for _, d := range defs {
+			if at, ok := added[d.ID]; ok {
+				unlocked = append(unlocked, achievementStatus{
+					Definition: d, Progress: d.Criteria.Target, Target: d.Criteria.Target,
+					Unlocked: true, UnlockedAt: &at,
+				})
+			}
+		}
+	}
+
+	if all {
+		for _, d := range defs {
+			s := achievementStatus{Definition: d, Progress: d.Criteria.Progress(stats), Target: d.Criteria.Target}
+			if at, ok := have[d.ID]; ok {
+				s.Unlocked, s.UnlockedAt, s.Progress = true, &at, d.Criteria.Target
+			}
+			statuses = append(statuses, s)
+		}
+	}
+	return statuses, unlocked, nil
+}
+
+// stats loads the parts of the achievement snapshot whose sources are needed
+func (t *progressTracker) stats(ctx context.Context, userID string, needed map[achievement.Source]bool) (*achievement.Stats, error) {
+	s := &achievement.Stats{}
+	var err error
+	if needed[achievement.SourceHabits] {
+		if s.HabitCompletions, err = t.habitRepo.CompletionCount(ctx, userID); err != nil {
+			return nil, err
+		}
+		days, err := t.habitRepo.CompletionDays(ctx, userID, streakLookbackDays)
+		if err != nil {
+			return nil, err
+		}
+		s.HabitStreak = achievement.Streak(days, time.Now())
+	}
+	if needed[achievement.SourcePlays] {
+		if s.GamePlays, s.BestScores, s.MultiplayerWins, err = t.playRepo.Totals(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	if needed[achievement.SourcePet] {
+		if s.PetLevel, s.PetStats, err = t.petStats(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// petStats reads the level and stats of the user's pet without locking or
+// creating it; a user without a pet has zero for everything
+func (t *progressTracker) petStats(ctx context.Context, userID string) (int, map[pet.Stat]int, error) {
+	state, err := t.petRepo.PeekStateForUser(ctx, userID)
+	if err != nil {
+		return 0, nil, err
+	}
+	stats := make(map[pet.Stat]int, len(pet.AllStats))
+	if state == nil {
+		return 0, stats, nil
+	}
+	for _, stat := range pet.AllStats {
+		stats[stat] = state.Get(stat)
+	}
+	return state.Level, stats, nil
+}
+
+// activeQuests returns the user's quests for the current periods, assigning
+// them from the pool first if the user has none for a period yet
+func (t *progressTracker) activeQuests(ctx context.Context, userID string, now time.Time) ([]repository.UserQuest, error) {
+	quests, err := t.questRepo.Active(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	assigned := map[string]bool{}
+	for _, uq := range quests {
+		assigned[uq.Period] = true
+	}
+
+	var missing []repository.UserQuest
+	for _, period := range quest.Periods {
+		if assigned[period] {
+			continue
+		}
+		start, end := quest.Bounds(period, now)
+		for _, d := range quest.Pick(userID, period, start) {
+			missing = append(missing, repository.UserQuest{
+				QuestID: d.ID, Period: period, PeriodStart: start, ExpiresAt: end,
+				Target: d.Target, RewardCoins: d.Reward.Coins, RewardEnergy: d.Reward.Energy,
+			})
+		}
+	}
+	if len(missing) == 0 {
+		return quests, nil
+	}
+	if err := t.questRepo.Assign(ctx, userID, missing); err != nil {
+		return nil, err
+	}
+	return t.questRepo.Active(ctx, userID, now)
+}
+
+// advanceQuests applies ev to the user's open quests. Pet stat quests are
+// checked against the pet's current state on every event, since stats also
+// change through decay and items.
+func (t *progressTracker) advanceQuests(ctx context.Context, userID string, ev quest.Event) error {
+	quests, err := t.activeQuests(ctx, userID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	watchPet := false
+	for _, uq := range quests {
+		if uq.ClaimedAt != nil {
+			continue
+		}
+		d, ok := quest.Lookup(uq.QuestID)
+		if !ok {
+			continue
+		}
+		if d.Type == quest.PetStat && uq.Progress < uq.Target {
+			watchPet = true
+		}
+		if ev.Kind == quest.EventHabitReopened &&
+			(ev.CompletedAt.Before(uq.PeriodStart) || !ev.CompletedAt.Before(uq.ExpiresAt)) {
+			continue
+		}
+		step, ok := d.Advance(ev)
+		if !ok {
+			continue
+		}
+		if err := t.questRepo.Advance(ctx, uq.ID, step.Delta, step.AtLeast); err != nil {
+			return err
+		}
+	}
+
+	if watchPet && ev.Kind != quest.EventPetStats {
+		_, stats, err := t.petStats(ctx, userID)
+		if err != nil {
+			return err
+		}
+		return t.advanceQuests(ctx, userID, quest.Event{Kind: quest.EventPetStats, Stats: stats})
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"fsd-backend/internal/db"
+	"fsd-backend/internal/middleware"
+	"fsd-backend/internal/quest"
+	"fsd-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type QuestController struct {
+	db            *pgxpool.Pool
+	questRepo     *repository.QuestRepo
+	energyRepo    *repository.EnergyRepo
+	inventoryRepo *repository.InventoryRepo
+	tracker       *progressTracker
+}
+
+func NewQuestController(db *pgxpool.Pool) *QuestController {
+	return &QuestController{
+		db:            db,
+		questRepo:     repository.NewQuestRepo(db),
+		energyRepo:    repository.NewEnergyRepo(db),
+		inventoryRepo: repository.NewInventoryRepo(db),
+		tracker:       newProgressTracker(db),
+	}
+}
+
+// questStatus is an assigned quest with its pool description
+type questStatus struct {
+	repository.UserQuest
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	Completed   bool   `json:"completed"`
+}
+
+func newQuestStatus(uq repository.UserQuest) questStatus {
+	s := questStatus{UserQuest: uq, Title: uq.QuestID, Completed: uq.Progress >= uq.Target}
+	if d, ok := quest.Lookup(uq.QuestID); ok {
+		s.Title, s.Description, s.Type = d.Title, d.Description, d.Type
+	}
+	return s
+}
+
+// GET /quests - List the user's daily and weekly quests with progress.
+// Quests for a new day or week are assigned from the pool on first access.
+func (ctl *QuestController) List(c *gin.Context) {
+	userID := middleware.UserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	// An empty event moves nothing but catches pet stat quests up with
+	// stats changed outside tracked events (decay, items)
+	if err := ctl.tracker.advanceQuests(c, userID, quest.Event{}); err != nil {
+		log.Printf("ERROR: Failed to advance quests for user %s: %v", userID, err)
+	}
+
+	quests, err := ctl.tracker.activeQuests(c, userID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch quests"})
+		return
+	}
+	data := make([]questStatus, 0, len(quests))
+	for _, uq := range quests {
+		data = append(data, newQuestStatus(uq))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+// POST /quests/:id/claim - Claim the reward of a completed quest.
+// Each quest can be claimed once, before its period ends.
+func (ctl *QuestController) Claim(c *gin.Context) {
+	userID := middleware.UserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quest not found"})
+		return
+	}
+
+	var claimed *repository.UserQuest
+	var energy, coins *int
+	err := db.WithTxnRetry(c, ctl.db, func(tx pgx.Tx) error {
+		energy, coins = nil, nil
+		var err error
+		claimed, err = ctl.questRepo.ClaimTx(c, tx, userID, id)
+		if err != nil {
+			return err
+		}
+		if claimed.RewardEnergy > 0 {
+			e, err := ctl.energyRepo.GrantOnceTx(c, tx, userID, claimed.RewardEnergy, repository.EnergyReasonQuest, claimed.ID)
+			if err != nil {
+				return err
+			}
+			energy = &e
+		}
+		if claimed.RewardCoins > 0 {
+			balance, err := ctl.inventoryRepo.AddCoinsTx(c, tx, userID, claimed.RewardCoins)
+			if err != nil {
+				return err
+			}
+			coins = &balance
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, repository.ErrQuestNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quest not found"})
+		return
+	case errors.Is(err, repository.ErrQuestClaimed), errors.Is(err, repository.ErrAlreadyGranted):
+		c.JSON(http.StatusConflict, gin.H{"error": "Quest reward already claimed"})
+		return
+	case errors.Is(err, repository.ErrQuestIncomplete):
+		c.JSON(http.StatusConflict, gin.H{"error": "Quest not complete"})
+		return
+	case errors.Is(err, repository.ErrQuestExpired):
+		c.JSON(http.StatusGone, gin.H{"error": "Quest expired"})
+		return
+	case err != nil:
+		log.Printf("ERROR: Failed to claim quest %s for user %s: %v", id, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to claim quest"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": newQuestStatus(*claimed),
+		"reward": quest.Reward{
+			Coins:  claimed.RewardCoins,
+			Energy: claimed.RewardEnergy,
+		},
+		"energy": energy,
+		"coins":  coins,
+	})
+}
@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"fsd-backend/internal/db"
+	"fsd-backend/internal/middleware"
+	"fsd-backend/internal/pet"
+	"fsd-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ShopController struct {
+	db      *pgxpool.Pool
+	repo    *repository.InventoryRepo
+	petRepo *repository.PetRepo
+}
+
+func NewShopController(db *pgxpool.Pool) *ShopController {
+	return &ShopController{
+		db:      db,
+		repo:    repository.NewInventoryRepo(db),
+		petRepo: repository.NewPetRepo(db),
+	}
+}
+
+// GET /shop/items - List the item catalogue with prices
+func (ctl *ShopController) ListItems(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": pet.AllItems()})
+}
+
+// GET /inventory - Get the user's coins and owned items
+func (ctl *ShopController) GetInventory(c *gin.Context) {
+	userID := middleware.UserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	coins, err := ctl.repo.GetCoins(c, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coins"})
+		return
+	}
+	items, err := ctl.repo.List(c, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch inventory"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"coins": coins, "items": items})
+}
+
+type buyItemReq struct {
+	ItemID   string `json:"item_id" binding:"required"`
+	Quantity int    `json:"quantity"`
+}
+
+// POST /shop/buy - Buy an item with coins
+func (ctl *ShopController) Buy(c *gin.Context) {
+	userID := middleware.UserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req buyItemReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+	if req.Quantity < 0 || req.Quantity > 99 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "quantity must be between 1 and 99"})
+		return
+	}
+
+	item, ok := pet.LookupItem(req.ItemID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "item not found"})
+		return
+	}
+	if item.Category == pet.ItemCosmetic {
+		// Cosmetics are worn, not used up, so one is enough
+		req.Quantity = 1
+	}
+	cost := item.Price * req.Quantity
+
+	// Debit and delivery commit together so concurrent purchases cannot overdraw
+	errOwned := errors.New("cosmetic already owned")
+	var coins, quantity int
+	err := db.WithTxnRetry(c, ctl.db, func(tx pgx.Tx) error {
+		if item.Category == pet.ItemCosmetic {
+			owned, err := ctl.repo.GetQuantityTx(c, tx, userID, item.ID)
+			if err != nil {
+				return err
+			}
+			if owned > 0 {
+				return errOwned
+			}
+		}
+
+		var err error
+		if coins, err = ctl.repo.SpendCoinsTx(c, tx, userID, cost); err != nil {
+			return err
+		}
+		quantity, err = ctl.repo.AddItemTx(c, tx, userID, item.ID, req.Quantity)
+		return err
+	})
+	if errors.Is(err, errOwned) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Item already owned"})
+		return
+	}
+	if errors.Is(err, repository.ErrInsufficientCoins) {
+		current, _ := ctl.repo.GetCoins(c, userID)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":          "Insufficient coins",
+			"current_coins":  current,
+			"required_coins": cost,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to buy item"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"item_id":  item.ID,
+		"quantity": quantity,
+		"cost":     cost,
+		"coins":    coins,
+	})
+}
+
+type useItemReq struct {
+	ItemID string `json:"item_id" binding:"required"`
+}
+
+// POST /inventory/use - Use a consumable item on the user's pet
+func (ctl *ShopController) Use(c *gin.Context) {
+	userID := middleware.UserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req useItemReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, ok := pet.LookupItem(req.ItemID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "item not found"})
+		return
+	}
+	if !item.Consumable() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "item cannot be used"})
+		return
+	}
+
+	errNotAllowed := errors.New("item not allowed for species")
+	var state *pet.State
+	var remaining int
+	err := db.WithTxnRetry(c, ctl.db, func(tx pgx.Tx) error {
+		p, err := ctl.petRepo.LockByUserIDTx(c, tx, userID)
+		if err != nil {
+			return err
+		}
+		if !pet.LookupSpecies(p.Species).AllowsItem(item.ID) {
+			return errNotAllowed
+		}
+		if remaining, err = ctl.repo.ConsumeItemTx(c, tx, userID, item.ID); err != nil {
+			return err
+		}
+		_, state, err = ctl.petRepo.UpdateStateTx(c, tx, p.ID, func(s *pet.State) error {
+			s.UseItem(item, time.Now())
+			return nil
+		})
+		return err
+	})
+	switch {
+	case errors.Is(err, errNotAllowed):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Your pet can't use this item"})
+		return
+	case errors.Is(err, repository.ErrItemNotOwned):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Item not in inventory"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to use item"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"item_id":  item.ID,
+		"quantity": remaining,
+		"state":    state,
+	})
+}
@@ -1,17 +1,24 @@
 package controllers
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
-	"math/rand"
 	"net/http"
-	"sync"
+	"strconv"
 	"time"
 
 	"fsd-backend/internal/auth"
+	"fsd-backend/internal/db"
 	"fsd-backend/internal/game"
+	"fsd-backend/internal/quest"
+	"fsd-backend/internal/repository"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var sunnySaysUpgrader = websocket.Upgrader{
@@ -20,60 +27,54 @@ var sunnySaysUpgrader = websocket.Upgrader{
 	},
 }
 
+// Message types from client. The messages the server sends are game.Message.
 const (
-	// Message types from client
 	MsgTypeJoinRoom    = "join_room"
 	MsgTypePlayerInput = "player_input"
-	MsgTypeWaitChoice  = "wait_choice" // "wait" or "singleplayer"
-	MsgTypeReady       = "ready"       // Client ready after countdown
-
-	// Message types from server
-	MsgTypeRoomJoined       = "room_joined"
-	MsgTypeRoomFull         = "room_full"
-	MsgTypeWaiting          = "waiting"
-	MsgTypeGameStart        = "game_start"
-	MsgTypeRoundStart       = "round_start"
-	MsgTypeSunnyFrame       = "sunny_frame"
-	MsgTypeOpponentFrame    = "opponent_frame"
-	MsgTypeRoundResult      = "round_result"
-	MsgTypeGameOver         = "game_over"
-	MsgTypeOpponentGameOver = "opponent_game_over"
-	MsgTypeError            = "error"
+	MsgTypeWaitChoice  = "wait_choice"  // "wait" or "singleplayer" (a solo game run by the server)
+	MsgTypeReady       = "ready"        // Client ready after countdown
+	MsgTypeStartMatch  = "start_match"  // Host starts a private room's match
+	MsgTypeRematchVote = "rematch_vote" // Accept or decline a rematch after game over
 )
 
 type ClientMessage struct {
 	Type   string `json:"type"`
 	Frame  int    `json:"frame,omitempty"`
-	Choice string `json:"choice,omitempty"` // "wait" or "singleplayer"
-}
-
-type ServerMessage struct {
-	Type              string `json:"type"`
-	RoomID            string `json:"room_id,omitempty"`
-	PlayerID          string `json:"player_id,omitempty"`
-	OpponentID        string `json:"opponent_id,omitempty"`
-	Frame             int    `json:"frame,omitempty"`
-	SunnyFrame        int    `json:"sunny_frame,omitempty"`
-	Score             int    `json:"score,omitempty"`
-	OpponentScore     int    `json:"opponent_score,omitempty"`
-	Round             int    `json:"round,omitempty"`
-	ConfusionSeq      []int  `json:"confusion_seq,omitempty"`
-	WaitTime          int    `json:"wait_time,omitempty"` // milliseconds
-	Message           string `json:"message,omitempty"`
-	DisplayDurationMs int    `json:"display_duration_ms"` // Duration in milliseconds for client to display this frame (0 = no duration, final frame)
+	Choice string `json:"choice,omitempty"` // "wait" or "singleplayer"; "accept" or "decline" for a rematch
 }
 
+// SunnySaysWSHandler connects players to Sunny Says rooms. Each room runs
+// the game on its own goroutine; the handler reads the players' messages,
+// hands them to their room and stores the results of finished matches.
 type SunnySaysWSHandler struct {
+	db          *pgxpool.Pool
 	roomManager *game.RoomManager
 	signer      *auth.Signer
-	connMutexes sync.Map // Map[*websocket.Conn]*sync.Mutex for thread-safe writes
+	petRepo     *repository.PetRepo
+	playRepo    *repository.GamePlayRepo
+	matchRepo   *repository.MatchRepo
+	ratingRepo  *repository.RatingRepo
+	sessionRepo *repository.GameSessionRepo
+	energyRepo  *repository.EnergyRepo
+	rewards     *gameRewards
+	tracker     *progressTracker
 }
 
-func NewSunnySaysWSHandler(signer *auth.Signer) *SunnySaysWSHandler {
-	return &SunnySaysWSHandler{
-		roomManager: game.NewRoomManager(),
+func NewSunnySaysWSHandler(signer *auth.Signer, db *pgxpool.Pool) *SunnySaysWSHandler {
+	h := &SunnySaysWSHandler{
+		db:          db,
 		signer:      signer,
+		petRepo:     repository.NewPetRepo(db),
+		playRepo:    repository.NewGamePlayRepo(db),
+		matchRepo:   repository.NewMatchRepo(db),
+		ratingRepo:  repository.NewRatingRepo(db),
+		sessionRepo: repository.NewGameSessionRepo(db),
+		energyRepo:  repository.NewEnergyRepo(db),
+		rewards:     newGameRewards(db),
+		tracker:     newProgressTracker(db),
 	}
+	h.roomManager = game.NewRoomManager(h.recordResult)
+	return h
 }
 
 func (h *SunnySaysWSHandler) HandleConnection(c *gin.Context) {
@@ -92,6 +93,18 @@ func (h *SunnySaysWSHandler) HandleConnection(c *gin.Context) {
 	}
 
 	userID := claims.UserID
+	resumeToken := c.Query("resume")
+
+	// Room size for new rooms: 2 for a 1v1 duel, up to 8 for a battle
+	size := game.DuelPlayers
+	if v := c.Query("players"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < game.MinRoomPlayers || n > game.MaxRoomPlayers {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("players must be between %d and %d", game.MinRoomPlayers, game.MaxRoomPlayers)})
+			return
+		}
+		size = n
+	}
 
 	// Upgrade to WebSocket
 	conn, err := sunnySaysUpgrader.Upgrade(c.Writer, c.Request, nil)
@@ -101,437 +114,398 @@ func (h *SunnySaysWSHandler) HandleConnection(c *gin.Context) {
 	}
 	defer conn.Close()
 
-	// Find or create room
-	room := h.roomManager.FindOrCreateRoom()
+	// Resume a match the player dropped out of
+	if resumeToken != "" {
+		h.resume(conn, userID, resumeToken)
+		return
+	}
 
 	// Create player
 	player := game.NewPlayer(userID, conn)
 
-	// Add player to room
-	if !room.AddPlayer(player) {
-		// Room is full, send error and close
-		h.sendMessage(conn, ServerMessage{
-			Type:    MsgTypeError,
-			Message: "Room is full",
-		})
-		return
+	// Load the player's pet so opponents can render it
+	appearance, err := h.petRepo.GetAppearanceByUserID(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to load pet for user %s: %v", userID, err)
 	}
+	player.Pet = appearance
 
-	// Send room joined message
-	h.sendMessage(conn, ServerMessage{
-		Type:     MsgTypeRoomJoined,
-		RoomID:   room.ID,
-		PlayerID: player.ID,
-	})
+	// Load the rating used to match the player (unrated players start at the default)
+	player.Rating = game.DefaultRating
+	if rating, err := h.ratingRepo.Get(c.Request.Context(), userID, game.SunnySaysID); err != nil {
+		log.Printf("Failed to load rating for user %s: %v", userID, err)
+	} else {
+		player.Rating = rating.Rating
+	}
 
-	// If room is now full, start game
-	if room.IsFull() {
-		opponent := room.GetOpponent(player.ID)
-		if opponent != nil {
-			// Notify both players game is starting
-			h.sendMessage(player.Conn, ServerMessage{
-				Type:       MsgTypeGameStart,
-				OpponentID: opponent.ID,
-			})
-			h.sendMessage(opponent.Conn, ServerMessage{
-				Type:       MsgTypeGameStart,
-				OpponentID: player.ID,
-			})
-
-			// Start game loop
-			go h.runGame(room)
+	// Play a solo game straight away
+	if c.Query("solo") == "true" {
+		if h.startSolo(player) {
+			h.handlePlayerMessages(player, conn)
 		}
-	} else {
-		// Waiting for second player
-		h.sendMessage(conn, ServerMessage{
-			Type: MsgTypeWaiting,
-		})
+		return
+	}
 
-		// Start waiting timeout
-		go h.handleWaitingTimeout(room, player)
+	// Create a private room, join one by code, or matchmake into a public room.
+	// The room tells the player they joined and starts the match once it can.
+	var room *game.SunnySaysRoom
+	if code := c.Query("code"); code != "" {
+		room = h.roomManager.FindRoomByCode(code)
+		if room == nil {
+			sendError(conn, "room_not_found")
+			return
+		}
+		if room.HasUser(userID) {
+			sendError(conn, "already_in_room")
+			return
+		}
+		if !room.Join(player) {
+			sendError(conn, "room_full")
+			return
+		}
+	} else if c.Query("private") == "true" {
+		room = h.roomManager.CreatePrivateRoom(player, size)
+	} else {
+		room = h.roomManager.Matchmake(player, size)
+	}
+	if room == nil {
+		return
 	}
 
 	// Handle incoming messages
-	h.handlePlayerMessages(room, player)
+	h.handlePlayerMessages(player, conn)
 }
 
-func (h *SunnySaysWSHandler) handleWaitingTimeout(room *game.SunnySaysRoom, player *game.Player) {
-	timeout := time.NewTimer(game.RoomWaitTimeout)
-	defer timeout.Stop()
+// GET /game/sunny-says/rooms - List the public Sunny Says matches in progress, oldest first
+func (h *SunnySaysWSHandler) ListRooms(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": h.roomManager.LiveRooms()})
+}
 
-	<-timeout.C
+// HandleSpectate streams a public match to a read-only spectator.
+// Query: token (access token), room (room_id from ListRooms)
+func (h *SunnySaysWSHandler) HandleSpectate(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing token"})
+		return
+	}
+	claims, err := h.signer.Parse(token)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+	room := h.roomManager.GetRoom(c.Query("room"))
+	if room == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "room not found"})
+		return
+	}
+
+	conn, err := sunnySaysUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
 
-	// Check if room is still waiting AND game hasn't started
-	// Don't send timeout if game has already started
-	if room.State == game.RoomStatePlaying {
-		// Game has started, don't send timeout message
+	// The room sends the spectator the match state, then everything that happens
+	spectator := game.NewSpectator(claims.UserID, conn)
+	if !room.AddSpectator(spectator) {
+		sendError(conn, "room_not_live")
 		return
 	}
+	defer room.RemoveSpectator(spectator)
+
+	// Spectators are read-only; read only to notice when they leave
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// startSolo pays for a solo game like POST /game/sessions and starts it in a
+// room of its own. It reports whether the game started; if not the player
+// has been sent an error.
+func (h *SunnySaysWSHandler) startSolo(player *game.Player) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	def, ok := game.LookupGame(game.SunnySaysID)
+	if !ok || !def.Enabled {
+		player.Send(game.Message{Type: game.MsgTypeError, Message: "game_disabled"})
+		return false
+	}
 
-	// Check if room is still waiting
-	isFull := room.IsFull()
+	var session *repository.GameSession
+	var energy int
+	err := db.WithTxnRetry(ctx, h.db, func(tx pgx.Tx) error {
+		var err error
+		if session, err = h.sessionRepo.CreateTx(ctx, tx, player.UserID, def.ID, def.SessionTTL()); err != nil {
+			return err
+		}
+		energy, err = h.energyRepo.SpendTx(ctx, tx, player.UserID, def.EnergyCost, repository.EnergyReasonGameSession, session.ID)
+		return err
+	})
+	if errors.Is(err, repository.ErrInsufficientEnergy) {
+		player.Send(game.Message{Type: game.MsgTypeError, Message: "insufficient_energy"})
+		return false
+	}
+	if err != nil {
+		log.Printf("Failed to start solo Sunny Says game for user %s: %v", player.UserID, err)
+		player.Send(game.Message{Type: game.MsgTypeError, Message: "solo_unavailable"})
+		return false
+	}
+
+	// The session expires unfinished if the player is already gone
+	return h.roomManager.CreateSoloRoom(player, session.ID, energy) != nil
+}
+
+// recordResult stores a finished match or solo game and returns what the
+// players are sent with game over. It runs off the room's goroutine.
+func (h *SunnySaysWSHandler) recordResult(res game.MatchResult) game.MatchRecord {
+	if res.Solo {
+		result := h.finishSolo(res)
+		if result == nil {
+			return game.MatchRecord{}
+		}
+		return game.MatchRecord{Rewards: map[string]any{res.Players[0].PlayerID: result}}
+	}
+
+	matchID, results := h.recordMatch(res)
+	rewards := make(map[string]any, len(results))
+	for playerID, result := range results {
+		rewards[playerID] = result
+	}
+	return game.MatchRecord{MatchID: matchID, Rewards: rewards}
+}
+
+// finishSolo closes a solo game's session with the score counted by the
+// server, records the play and applies its rewards. It returns nil if
+// saving failed.
+func (h *SunnySaysWSHandler) finishSolo(res game.MatchResult) *gameResult {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if len(res.Players) == 0 {
+		return nil
+	}
+	p := res.Players[0]
+	def, ok := game.LookupGame(game.SunnySaysID)
+	if !ok {
+		log.Printf("Failed to save solo Sunny Says game for user %s: game missing from catalog", p.UserID)
+		return nil
+	}
 
-	if !isFull {
-		// 10 seconds passed, ask player to wait or play singleplayer
-		// Only send if player is still in the room and game hasn't started
-		if room.GetPlayer(player.ID) != nil {
-			h.sendMessage(player.Conn, ServerMessage{
-				Type:    MsgTypeWaiting,
-				Message: "timeout",
-			})
+	var result *gameResult
+	err := db.WithTxnRetry(ctx, h.db, func(tx pgx.Tx) error {
+		finished, err := h.sessionRepo.FinishTx(ctx, tx, res.SessionID, repository.GameSessionFinished, p.Score)
+		if err != nil {
+			return err
 		}
+		sessionID := finished.ID
+		err = h.playRepo.RecordTx(ctx, tx, &repository.GamePlay{
+			UserID:     p.UserID,
+			GameID:     def.ID,
+			Score:      p.Score,
+			DurationMs: int(finished.FinishedAt.Sub(res.StartedAt).Milliseconds()),
+			Mode:       repository.PlayModeSolo,
+			SessionID:  &sessionID,
+			StartedAt:  res.StartedAt,
+			EndedAt:    *finished.FinishedAt,
+		})
+		if err != nil {
+			return err
+		}
+		result, err = h.rewards.applyTx(ctx, tx, p.UserID, def, p.Score, true)
+		return err
+	})
+	if err != nil {
+		log.Printf("Failed to save solo Sunny Says game for user %s: %v", p.UserID, err)
+		return nil
+	}
+	h.tracker.afterEvent(ctx, p.UserID, quest.Event{
+		Kind:   quest.EventGamePlayed,
+		GameID: def.ID,
+		Score:  p.Score,
+	})
+	return result
+}
+
+// resume moves a player who dropped out of a running match to conn. The
+// room sends them the current state of the match.
+func (h *SunnySaysWSHandler) resume(conn *websocket.Conn, userID, token string) {
+	player := h.roomManager.FindPlayer(token)
+	if player == nil || !player.Room().Resume(player, conn, userID) {
+		sendError(conn, "resume_failed")
+		return
 	}
+	h.handlePlayerMessages(player, conn)
 }
 
-func (h *SunnySaysWSHandler) handlePlayerMessages(room *game.SunnySaysRoom, player *game.Player) {
+// handlePlayerMessages hands the player's messages on conn to their room
+// until the connection closes
+func (h *SunnySaysWSHandler) handlePlayerMessages(player *game.Player, conn *websocket.Conn) {
 	for {
 		var msg ClientMessage
-		err := player.Conn.ReadJSON(&msg)
+		err := conn.ReadJSON(&msg)
 		if err != nil {
 			log.Printf("Error reading message from player %s: %v", player.ID, err)
 			break
 		}
 
+		// Matchmaking may have moved the player to another room while waiting
+		room := player.Room()
+
 		switch msg.Type {
 		case MsgTypePlayerInput:
-			// Update player's frame
-			player.SetFrame(msg.Frame)
-
-			// Broadcast to opponent
-			opponent := room.GetOpponent(player.ID)
-			if opponent != nil && !opponent.IsGameOver() {
-				h.sendMessage(opponent.Conn, ServerMessage{
-					Type:  MsgTypeOpponentFrame,
-					Frame: msg.Frame,
-				})
-			}
+			room.Input(player, msg.Frame)
 
 		case MsgTypeWaitChoice:
 			if msg.Choice == "wait" {
-				// Wait another 10 seconds - send waiting message to show searching text
-				h.sendMessage(player.Conn, ServerMessage{
-					Type: MsgTypeWaiting,
-				})
-				go h.handleWaitingTimeout(room, player)
+				room.KeepWaiting(player)
 			} else if msg.Choice == "singleplayer" {
-				// Remove player from room and let them play singleplayer
-				room.RemovePlayer(player.ID)
-				// Client should handle singleplayer mode
-				h.sendMessage(player.Conn, ServerMessage{
-					Type:    MsgTypeError,
-					Message: "singleplayer_mode",
-				})
-				return
-			}
-
-		case MsgTypeReady:
-			// Player is ready after countdown
-			player.SetReady(true)
-			// Check if all players are ready and no round is currently active
-			if room.AllPlayersReady() && !room.IsRoundActive() {
-				// Both players ready, start round in goroutine
-				room.ResetReady() // Reset for next round
-				go h.startRound(room)
-			}
-		}
-	}
-
-	// Player disconnected
-	// Get opponent BEFORE removing player (so we can notify them)
-	var opponent *game.Player
-	if room.State == game.RoomStatePlaying {
-		opponent = room.GetOpponent(player.ID)
-	} else {
-		// Game hasn't started - get remaining player if any
-		if len(room.Players) > 1 {
-			for _, p := range room.Players {
-				if p.ID != player.ID {
-					opponent = p
-					break
+				// Leave the queue and play a solo game run by the server
+				if !room.LeaveQueue(player) {
+					continue
+				}
+				if !h.startSolo(player) {
+					return
 				}
 			}
-		}
-	}
 
-	// Remove player from room
-	room.RemovePlayer(player.ID)
+		case MsgTypeStartMatch:
+			room.Start(player)
 
-	// Notify opponent if game has started and they're still active
-	if room.State == game.RoomStatePlaying && opponent != nil && !opponent.IsGameOver() {
-		// Opponent is still playing - notify them that opponent disconnected
-		// They can continue playing solo - connection stays open
-		// Send message, but don't close connection even if there's an error
-		if err := h.sendMessageSafe(opponent.Conn, ServerMessage{
-			Type: MsgTypeOpponentGameOver,
-		}); err != nil {
-			log.Printf("Failed to notify opponent of disconnect: %v", err)
-			// Don't close connection - let them continue playing
-		}
+		case MsgTypeRematchVote:
+			room.VoteRematch(player, msg.Choice == "accept")
 
-		// If a round is active and only one player remains, mark round as inactive
-		// This allows the remaining player to continue playing
-		if room.IsRoundActive() && len(room.Players) == 1 {
-			room.SetRoundActive(false)
-			// Reset ready status so the remaining player can send ready to start next round
-			room.ResetReady()
+		case MsgTypeReady:
+			room.Ready(player)
 		}
-	} else if room.State != game.RoomStatePlaying && opponent != nil {
-		// Game hasn't started yet - notify opponent
-		h.sendMessage(opponent.Conn, ServerMessage{
-			Type:    MsgTypeError,
-			Message: "opponent_disconnected",
-		})
 	}
 
-	// Clean up room only if empty
-	// Note: We don't check AllPlayersGameOver() here because game-over players
-	// can disconnect and leave, allowing them to join new games
-	if len(room.Players) == 0 {
-		h.roomManager.RemoveRoom(room.ID)
-	}
+	// Mid-match the player keeps their slot for a while so they can resume
+	player.Disconnect(conn)
 }
 
-func (h *SunnySaysWSHandler) runGame(room *game.SunnySaysRoom) {
-	// Wait for both players to be ready (after countdown)
-	// This is handled by the ready message system
-	// The first round will start when both players send ready
-
-	// Game loop - wait for ready messages to trigger rounds
-	for {
-		// Check if all players game over
-		if room.AllPlayersGameOver() {
-			// Notify both players
-			for _, p := range room.Players {
-				h.sendMessage(p.Conn, ServerMessage{
-					Type: MsgTypeGameOver,
-				})
-			}
-			room.State = game.RoomStateEnded
-			h.roomManager.RemoveRoom(room.ID)
-			return
-		}
-
-		// Wait a bit to avoid busy loop
-		time.Sleep(100 * time.Millisecond)
+// recordMatch stores a finished match with one game_plays row per participant,
+// updates the ratings of a rated duel and applies each participant's rewards
+// for their verified score, all in one transaction. It returns the match ID and the rewards by player ID, or an
+// empty ID if saving failed.
+func (h *SunnySaysWSHandler) recordMatch(res game.MatchResult) (string, map[string]*gameResult) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	def, ok := game.LookupGame(game.SunnySaysID)
+	if !ok {
+		log.Printf("Failed to record Sunny Says match %s: game missing from catalog", res.RoomID)
+		return "", nil
 	}
-}
 
-func (h *SunnySaysWSHandler) startRound(room *game.SunnySaysRoom) {
-	// Prevent multiple rounds from running simultaneously
-	if room.IsRoundActive() {
-		log.Printf("Round already active, skipping startRound")
-		return
+	match := &repository.Match{
+		GameID:     def.ID,
+		RoomID:     res.RoomID,
+		Private:    res.Private,
+		Rounds:     res.Rounds,
+		StartedAt:  res.StartedAt,
+		EndedAt:    res.EndedAt,
+		DurationMs: int(res.EndedAt.Sub(res.StartedAt).Milliseconds()),
 	}
-
-	// Check if all players are game over - if so, don't start a new round
-	if room.AllPlayersGameOver() {
-		log.Printf("All players game over, not starting new round")
-		return
+	if res.Winner != nil {
+		winnerID := res.Winner.UserID
+		match.WinnerID = &winnerID
 	}
 
-	// Check if there are any active (non-game-over) players
-	hasActivePlayers := false
-	for _, p := range room.Players {
-		if !p.IsGameOver() {
-			hasActivePlayers = true
-			break
+	plays := make([]*repository.GamePlay, 0, len(res.Players))
+	for i, p := range res.Players {
+		outcome := p.Outcome
+		play := &repository.GamePlay{
+			UserID:     p.UserID,
+			GameID:     def.ID,
+			Score:      p.Score,
+			DurationMs: int(p.EndedAt.Sub(res.StartedAt).Milliseconds()),
+			Mode:       repository.PlayModeMultiplayer,
+			Outcome:    &outcome,
+			StartedAt:  res.StartedAt,
+			EndedAt:    p.EndedAt,
 		}
-	}
-	if !hasActivePlayers {
-		log.Printf("No active players, not starting new round")
-		return
-	}
-
-	// Game constants
-	const (
-		minWaitTime  = 500 * time.Millisecond
-		maxWaitTime  = 3000 * time.Millisecond
-		matchTimeout = 1000 * time.Millisecond // 1 second for players to match
-	)
-
-	// Start new round
-	room.IncrementRound()
-	room.SetRoundActive(true) // Mark round as active immediately
-
-	// Reset active players to frame 0 and send round start message
-	for _, p := range room.Players {
-		if !p.IsGameOver() {
-			p.SetFrame(0)
-			h.sendMessage(p.Conn, ServerMessage{
-				Type:  MsgTypeRoundStart,
-				Round: room.CurrentRound,
-			})
+		if p.OpponentUserID != "" {
+			opponentID := p.OpponentUserID
+			play.OpponentID = &opponentID
 		}
+		plays = append(plays, play)
+		match.Players = append(match.Players, repository.MatchPlayer{
+			Place:   i + 1,
+			UserID:  p.UserID,
+			Score:   p.Score,
+			Outcome: p.Outcome,
+			Forfeit: p.Forfeit,
+			EndedAt: p.EndedAt,
+		})
 	}
 
-	// Wait random time before Sunny shows symbol (0.5 to 3 seconds)
-	waitMs := 500 + rand.Int63n(2500) // 500ms to 3000ms
-	time.Sleep(time.Duration(waitMs) * time.Millisecond)
-
-	// Check if confusion should be used
-	var sunnyFrame int
-	useConfusion := room.ShouldUseConfusion()
-
-	if useConfusion {
-		// Run confusion sequence - flash 1-3 times, then show final symbol
-		flashCount := rand.Intn(3) + 1 // 1 to 3 flashes
-
-		// Flash symbols
-		for i := 0; i < flashCount; i++ {
-			// Choose random symbol for this flash
-			flashFrame := room.ChooseRandomSymbol()
-			room.SetSunnyFrame(flashFrame)
-
-			// Send flash frame with 300ms duration to all players
-			duration300 := 300
-			for _, p := range room.Players {
-				if !p.IsGameOver() {
-					h.sendMessage(p.Conn, ServerMessage{
-						Type:              MsgTypeSunnyFrame,
-						SunnyFrame:        flashFrame,
-						DisplayDurationMs: duration300, // Client should display this for 300ms
-					})
-					// Small delay to ensure messages are properly spaced
-					time.Sleep(10 * time.Millisecond)
-				}
-			}
-
-			// Server waits for the flash duration (keeps server in sync)
-			time.Sleep(300 * time.Millisecond)
-
-			// If not the last flash, wait random time, reset to idle, then wait briefly
-			if i < flashCount-1 {
-				// Wait random time between flashes (0.3 to 1.0 seconds)
-				flashWaitMs := 300 + rand.Int63n(700)  // 300ms to 1000ms
-				totalIdleDuration := flashWaitMs + 100 // flashWaitMs + brief pause
-
-				// Reset to idle (frame 0) with combined duration
-				room.SetSunnyFrame(0)
-				idleDuration := int(totalIdleDuration)
-				for _, p := range room.Players {
-					if !p.IsGameOver() {
-						h.sendMessage(p.Conn, ServerMessage{
-							Type:              MsgTypeSunnyFrame,
-							SunnyFrame:        0,
-							DisplayDurationMs: idleDuration, // Combined wait time
-						})
-						// Small delay to ensure messages are properly spaced
-						time.Sleep(10 * time.Millisecond)
-					}
-				}
+	// Only matchmade duels are rated; private rooms are friendly matches
+	rated := !res.Private && !res.Battle && len(res.Players) == 2
 
-				// Server waits for the total idle duration (keeps server in sync)
-				time.Sleep(time.Duration(totalIdleDuration) * time.Millisecond)
-			}
+	var results map[string]*gameResult
+	err := db.WithTxnRetry(ctx, h.db, func(tx pgx.Tx) error {
+		results = make(map[string]*gameResult, len(plays))
+		if err := h.matchRepo.CreateTx(ctx, tx, match); err != nil {
+			return err
 		}
-
-		// Show final symbol (after all flashes) - no duration needed, this is the final symbol for the round
-		sunnyFrame = room.ChooseRandomSymbol()
-		room.SetSunnyFrame(sunnyFrame)
-		finalDuration := 0 // 0 means no duration, final frame
-		for _, p := range room.Players {
-			if !p.IsGameOver() {
-				h.sendMessage(p.Conn, ServerMessage{
-					Type:              MsgTypeSunnyFrame,
-					SunnyFrame:        sunnyFrame,
-					DisplayDurationMs: finalDuration, // 0 = final frame, no duration
-				})
-				// Small delay to ensure messages are properly spaced
-				time.Sleep(10 * time.Millisecond)
+		if rated {
+			if err := h.updateRatingsTx(ctx, tx, res.Players[0], res.Players[1]); err != nil {
+				return err
 			}
 		}
-	} else {
-		// Normal round - Sunny shows symbol immediately (no confusion)
-		sunnyFrame = room.ChooseRandomSymbol()
-		room.SetSunnyFrame(sunnyFrame)
-
-		// Broadcast Sunny frame (no duration, this is the final symbol for the round)
-		normalDuration := 0 // 0 means no duration, final frame
-		for _, p := range room.Players {
-			if !p.IsGameOver() {
-				h.sendMessage(p.Conn, ServerMessage{
-					Type:              MsgTypeSunnyFrame,
-					SunnyFrame:        sunnyFrame,
-					DisplayDurationMs: normalDuration, // 0 = final frame, no duration
-				})
+		for i, play := range plays {
+			play.MatchID = &match.ID
+			if err := h.playRepo.RecordTx(ctx, tx, play); err != nil {
+				return err
 			}
-		}
-	}
-
-	// Round is already active (set at start of function)
-	// Wait for match timeout
-	time.Sleep(matchTimeout)
-
-	// Check results for each player
-	for _, p := range room.Players {
-		if p.IsGameOver() {
-			continue
-		}
-
-		playerFrame := p.GetFrame()
-		matched := playerFrame == sunnyFrame && playerFrame != 0
-
-		if matched {
-			// Match successful
-			p.Score++
-			h.sendMessage(p.Conn, ServerMessage{
-				Type:  MsgTypeRoundResult,
-				Score: p.Score,
-				Frame: playerFrame,
-			})
-		} else {
-			// Game over for this player
-			p.SetGameOver()
-			h.sendMessage(p.Conn, ServerMessage{
-				Type:  MsgTypeGameOver,
-				Score: p.Score,
-			})
-
-			// Notify opponent
-			opponent := room.GetOpponent(p.ID)
-			if opponent != nil {
-				h.sendMessage(opponent.Conn, ServerMessage{
-					Type: MsgTypeOpponentGameOver,
-				})
+			// Multiplayer matches cost no energy, so they pay no coins
+			result, err := h.rewards.applyTx(ctx, tx, play.UserID, def, play.Score, false)
+			if err != nil {
+				return err
 			}
+			results[res.Players[i].PlayerID] = result
 		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to record Sunny Says match %s: %v", res.RoomID, err)
+		return "", nil
 	}
 
-	// Wait before next round
-	time.Sleep(500 * time.Millisecond)
-
-	// Mark round as inactive - ready for next round
-	room.SetRoundActive(false)
-
-	// Reset ready status for next round
-	room.ResetReady()
-
-	// Don't recursively call startRound - wait for ready messages to trigger next round
-	// The ready message handler will start the next round when both players are ready
+	for _, play := range plays {
+		h.tracker.afterEvent(ctx, play.UserID, quest.Event{
+			Kind:   quest.EventGamePlayed,
+			GameID: def.ID,
+			Score:  play.Score,
+			Won:    *play.Outcome == repository.OutcomeWin,
+		})
+	}
+	return match.ID, results
 }
 
-func (h *SunnySaysWSHandler) sendMessage(conn *websocket.Conn, msg ServerMessage) {
-	// Get or create mutex for this connection
-	mutexInterface, _ := h.connMutexes.LoadOrStore(conn, &sync.Mutex{})
-	writeMu := mutexInterface.(*sync.Mutex)
-
-	writeMu.Lock()
-	defer writeMu.Unlock()
-
-	if err := conn.WriteJSON(msg); err != nil {
-		log.Printf("Error sending message: %v", err)
+// updateRatingsTx applies the Elo result of a 1v1 match to both players inside tx.
+// A match nobody won, e.g. because both forfeited, counts as a draw.
+func (h *SunnySaysWSHandler) updateRatingsTx(ctx context.Context, tx pgx.Tx, a, b game.PlayerResult) error {
+	scoreA := 0.5
+	switch {
+	case a.Outcome == repository.OutcomeWin:
+		scoreA = 1
+	case b.Outcome == repository.OutcomeWin:
+		scoreA = 0
 	}
+	_, _, err := h.ratingRepo.ApplyMatchTx(ctx, tx, game.SunnySaysID, a.UserID, b.UserID, scoreA)
+	return err
 }
 
-// sendMessageSafe sends a message and returns an error if it fails
-// This is useful when we want to handle errors without closing connections
-func (h *SunnySaysWSHandler) sendMessageSafe(conn *websocket.Conn, msg ServerMessage) error {
-	// Get or create mutex for this connection
-	mutexInterface, _ := h.connMutexes.LoadOrStore(conn, &sync.Mutex{})
-	writeMu := mutexInterface.(*sync.Mutex)
-
-	writeMu.Lock()
-	defer writeMu.Unlock()
-
-	return conn.WriteJSON(msg)
+// sendError tells a connection that never joined a room why it was turned away
+func sendError(conn *websocket.Conn, message string) {
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.WriteJSON(game.Message{Type: game.MsgTypeError, Message: message}); err != nil {
+		log.Printf("Error sending message: %v", err)
+	}
 }
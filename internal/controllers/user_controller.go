@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -11,10 +12,17 @@ import (
 	"fsd-backend/internal/repository"
 )
 
-type UserController struct{ repo *repository.UserRepo }
-func NewUserController(db *pgxpool.Pool) *UserController { return &UserController{repo: repository.NewUserRepo(db)} }
+type UserController struct {
+	db         *pgxpool.Pool
+	repo       *repository.UserRepo
+	energyRepo *repository.EnergyRepo
+}
+func NewUserController(db *pgxpool.Pool) *UserController {
+	return &UserController{db: db, repo: repository.NewUserRepo(db), energyRepo: repository.NewEnergyRepo(db)}
+}
 
-// GET /users/me/energy - Get current user's energy
+// GET /users/me/energy - Get current user's energy, with when the next regenerated point
+// arrives and when regeneration is full
 func (ctl *UserController) GetEnergy(c *gin.Context) {
 	userID := middleware.UserID(c)
 	if userID == "" {
@@ -22,38 +30,86 @@ func (ctl *UserController) GetEnergy(c *gin.Context) {
 		return
 	}
 	
-	energy, err := ctl.repo.GetEnergy(c, userID)
+	status, err := ctl.energyRepo.GetStatus(c, userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch energy"})
 		return
 	}
 	
-	c.JSON(http.StatusOK, gin.H{"energy": energy})
+	c.JSON(http.StatusOK, status)
+}
+
+type timezoneReq struct {
+	Timezone string `json:"timezone" binding:"required"`
 }
 
-// PUT /users/me/energy - Update current user's energy
-func (ctl *UserController) UpdateEnergy(c *gin.Context) {
+// PUT /users/me/timezone - Set the IANA time zone (e.g. "Europe/Berlin") login days are counted in
+func (ctl *UserController) UpdateTimezone(c *gin.Context) {
 	userID := middleware.UserID(c)
 	if userID == "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
-	
-	var req struct {
-		Energy int `json:"energy" binding:"required"`
-	}
-	
+
+	var req timezoneReq
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	
-	if err := ctl.repo.UpdateEnergy(c, userID, req.Energy); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update energy"})
+	// "Local" would mean the server's zone
+	if _, err := time.LoadLocation(req.Timezone); err != nil || req.Timezone == "Local" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown time zone"})
 		return
 	}
-	
-	c.JSON(http.StatusOK, gin.H{"energy": req.Energy})
+	if err := ctl.repo.SetTimezone(c, userID, req.Timezone); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update time zone"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"timezone": req.Timezone})
+}
+
+// GET /users/me/energy/history - Get the user's energy changes, newest first.
+// Query: limit (1-100, default 20), cursor (next_cursor of the previous page)
+func (ctl *UserController) GetEnergyHistory(c *gin.Context) {
+	userID := middleware.UserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	energyHistory(c, ctl.energyRepo, userID)
+}
+
+// energyHistory writes a page of userID's energy ledger using the limit and cursor query parameters
+func energyHistory(c *gin.Context, repo *repository.EnergyRepo, userID string) {
+	limit, ok := intQuery(c, "limit", 20, 1, 100)
+	if !ok {
+		return
+	}
+	var afterCreatedAt *time.Time
+	var afterID string
+	if cursor := c.Query("cursor"); cursor != "" {
+		t, id, err := decodeCursor(cursor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		afterCreatedAt, afterID = &t, id
+	}
+
+	entries, err := repo.History(c, userID, afterCreatedAt, afterID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch energy history"})
+		return
+	}
+
+	var next *string
+	if len(entries) == limit {
+		last := entries[len(entries)-1]
+		cursor := encodeCursor(last.CreatedAt, last.ID)
+		next = &cursor
+	}
+	c.JSON(http.StatusOK, gin.H{"data": entries, "next_cursor": next})
 }
 
 func (ctl *UserController) List(c *gin.Context) {
@@ -0,0 +1,105 @@
+package energy
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Max is the most energy a user can hold; users.energy is checked against it
+const Max = 100
+
+// Regen describes passive energy regeneration: Points are added for every full
+// Every interval since the regen clock last moved, until energy reaches Cap.
+// Energy above Cap (e.g. from habits) is kept but does not regenerate further.
+type Regen struct {
+	Points int
+	Every  time.Duration
+	Cap    int
+}
+
+// DefaultRegen refills an empty bar to the starting 30 energy in three hours
+var DefaultRegen = Regen{Points: 1, Every: 6 * time.Minute, Cap: 30}
+
+// Validate reports settings that would never regenerate, divide by zero or
+// regenerate past Max
+func (r Regen) Validate() error {
+	if r.Points <= 0 {
+		return fmt.Errorf("energy regen points must be positive, got %d", r.Points)
+	}
+	if r.Every <= 0 {
+		return fmt.Errorf("energy regen interval must be positive, got %s", r.Every)
+	}
+	if r.Cap < 0 || r.Cap > Max {
+		return fmt.Errorf("energy regen cap must be between 0 and %d, got %d", Max, r.Cap)
+	}
+	return nil
+}
+
+// Apply returns energy after regenerating from the clock value since up to now,
+// and the new clock value. Only whole intervals are consumed so partial progress
+// towards the next point is kept; at or above the cap the clock restarts at now.
+func (r Regen) Apply(energy int, since, now time.Time) (int, time.Time) {
+	if energy >= r.Cap {
+		return energy, now
+	}
+	if !now.After(since) {
+		return energy, since
+	}
+	intervals := int(now.Sub(since) / r.Every)
+	if intervals == 0 {
+		return energy, since
+	}
+	energy += intervals * r.Points
+	if energy >= r.Cap {
+		return r.Cap, now
+	}
+	return energy, since.Add(time.Duration(intervals) * r.Every)
+}
+
+// Status is the regenerated energy with countdowns for the client
+type Status struct {
+	Energy int `json:"energy"`
+	Cap    int `json:"regen_cap"`
+	// NextPointAt and FullAt are nil when energy is already at or above the cap
+	NextPointAt *time.Time `json:"next_point_at"`
+	FullAt      *time.Time `json:"full_at"`
+}
+
+// Status applies regeneration at now and computes when the next point arrives
+// and when the cap is reached
+func (r Regen) Status(energy int, since, now time.Time) Status {
+	energy, since = r.Apply(energy, since, now)
+	s := Status{Energy: energy, Cap: r.Cap}
+	if energy >= r.Cap {
+		return s
+	}
+	next := since.Add(r.Every)
+	steps := (r.Cap - energy + r.Points - 1) / r.Points
+	full := since.Add(time.Duration(steps) * r.Every)
+	s.NextPointAt, s.FullAt = &next, &full
+	return s
+}
+
+var (
+	regenMu sync.RWMutex
+	regen   = DefaultRegen
+)
+
+// UseRegen replaces the regeneration settings used by the package
+func UseRegen(r Regen) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	regenMu.Lock()
+	defer regenMu.Unlock()
+	regen = r
+	return nil
+}
+
+// CurrentRegen returns the regeneration settings in use
+func CurrentRegen() Regen {
+	regenMu.RLock()
+	defer regenMu.RUnlock()
+	return regen
+}
@@ -0,0 +1,178 @@
+package game
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SunnySaysID is the catalogue id of the multiplayer Sunny Says game
+const SunnySaysID = "sunny_says"
+
+//go:embed games.json
+var embeddedCatalog []byte
+
+// MoodFormula turns a score into a pet mood increase:
+// round(score * PerPoint) + Bonus, capped at Max when Max > 0
+type MoodFormula struct {
+	PerPoint float64 `json:"per_point"`
+	Bonus    int     `json:"bonus,omitempty"`
+	Max      int     `json:"max,omitempty"`
+}
+
+// Apply returns the mood increase for score
+func (f MoodFormula) Apply(score int) int {
+	mood := int(math.Round(float64(score)*f.PerPoint)) + f.Bonus
+	if f.Max > 0 && mood > f.Max {
+		mood = f.Max
+	}
+	if mood < 0 {
+		mood = 0
+	}
+	return mood
+}
+
+// Definition describes a minigame and the rewards it gives
+type Definition struct {
+	ID            string      `json:"id"`
+	Title         string      `json:"title"` // Display name, also the game_type clients send and games.title
+	EnergyCost    int         `json:"energy_cost"`
+	Mood          MoodFormula `json:"mood"`
+	XPPerPoint    float64     `json:"xp_per_point"`
+	CoinsPerPoint float64     `json:"coins_per_point"`
+	MaxScore      int         `json:"max_score"`
+	Enabled       bool        `json:"enabled"`
+
+	// Plausibility limits for server-tracked sessions: a finished session may
+	// score at most MaxPointsPerSecond per second played plus ScoreGrace
+	MaxPointsPerSecond float64 `json:"max_points_per_second,omitempty"`
+	ScoreGrace         int     `json:"score_grace,omitempty"`
+	// SessionSeconds is how long a session stays open (default 15 minutes)
+	SessionSeconds int `json:"session_seconds,omitempty"`
+}
+
+const defaultSessionSeconds = 15 * 60
+
+// XP returns the pet XP earned for score
+func (d *Definition) XP(score int) int {
+	return int(math.Round(float64(score) * d.XPPerPoint))
+}
+
+// Coins returns the coins earned for score
+func (d *Definition) Coins(score int) int {
+	return int(math.Round(float64(score) * d.CoinsPerPoint))
+}
+
+// ValidScore reports whether score is within the game's limits
+func (d *Definition) ValidScore(score int) bool {
+	return score >= 0 && (d.MaxScore <= 0 || score <= d.MaxScore)
+}
+
+// SessionTTL is how long a game session may stay open
+func (d *Definition) SessionTTL() time.Duration {
+	if d.SessionSeconds > 0 {
+		return time.Duration(d.SessionSeconds) * time.Second
+	}
+	return defaultSessionSeconds * time.Second
+}
+
+// PlausibleScore reports whether score could have been reached in elapsed time
+func (d *Definition) PlausibleScore(score int, elapsed time.Duration) bool {
+	if !d.ValidScore(score) {
+		return false
+	}
+	if d.MaxPointsPerSecond <= 0 {
+		return true
+	}
+	limit := int(elapsed.Seconds()*d.MaxPointsPerSecond) + d.ScoreGrace
+	return score <= limit
+}
+
+// Catalog holds the loaded game definitions
+type Catalog struct {
+	byKey map[string]*Definition
+	order []*Definition
+}
+
+// ParseCatalog parses and validates a JSON array of game definitions
+func ParseCatalog(data []byte) (*Catalog, error) {
+	var list []*Definition
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("parse game catalog: %w", err)
+	}
+
+	cat := &Catalog{byKey: make(map[string]*Definition, 2*len(list))}
+	for _, d := range list {
+		if d.ID == "" || d.Title == "" {
+			return nil, fmt.Errorf("game definitions need an id and a title")
+		}
+		if d.EnergyCost < 0 {
+			return nil, fmt.Errorf("game %q: negative energy cost", d.ID)
+		}
+		for _, key := range []string{d.ID, strings.ToLower(d.Title)} {
+			if _, dup := cat.byKey[key]; dup {
+				return nil, fmt.Errorf("duplicate game %q", key)
+			}
+			cat.byKey[key] = d
+		}
+		cat.order = append(cat.order, d)
+	}
+	return cat, nil
+}
+
+// LoadCatalogFile reads game definitions from path
+func LoadCatalogFile(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCatalog(data)
+}
+
+// Lookup finds a game by id or (case-insensitive) title
+func (c *Catalog) Lookup(key string) (*Definition, bool) {
+	if d, ok := c.byKey[key]; ok {
+		return d, true
+	}
+	d, ok := c.byKey[strings.ToLower(key)]
+	return d, ok
+}
+
+var (
+	catalogMu sync.RWMutex
+	catalog   *Catalog
+)
+
+func init() {
+	cat, err := ParseCatalog(embeddedCatalog)
+	if err != nil {
+		panic(err)
+	}
+	catalog = cat
+}
+
+// UseCatalog replaces the game definitions used by the package
+func UseCatalog(cat *Catalog) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	catalog = cat
+}
+
+// LookupGame finds a game by id or title
+func LookupGame(key string) (*Definition, bool) {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	return catalog.Lookup(key)
+}
+
+// AllGames returns every game definition, including disabled ones
+func AllGames() []*Definition {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	return catalog.order
+}
@@ -0,0 +1,50 @@
+package game
+
+import (
+	"math"
+	"time"
+)
+
+// DefaultRating is the Elo rating of a player's first rated match
+const DefaultRating = 1200
+
+// Matchmaking pairs players whose ratings differ by at most a window that
+// starts at ratingWindowBase and grows by ratingWindowGrowth per second waited,
+// so nobody waits long for a close match when few players are online
+const (
+	ratingWindowBase   = 100
+	ratingWindowGrowth = 25
+	ratingWindowMax    = 1000
+)
+
+// EloK is the K-factor for a player with the given number of rated matches.
+// Provisional players move faster so they reach their level sooner.
+func EloK(matches int) int {
+	if matches < 30 {
+		return 40
+	}
+	return 20
+}
+
+// EloExpected returns the expected score of a player rated a against one rated b
+func EloExpected(a, b int) float64 {
+	return 1 / (1 + math.Pow(10, float64(b-a)/400))
+}
+
+// EloUpdate returns both players' new ratings after a scored scoreA
+// (1 for a win, 0.5 for a draw, 0 for a loss) against b
+func EloUpdate(a, b int, scoreA float64, kA, kB int) (int, int) {
+	expectedA := EloExpected(a, b)
+	newA := a + int(math.Round(float64(kA)*(scoreA-expectedA)))
+	newB := b + int(math.Round(float64(kB)*(expectedA-scoreA)))
+	return newA, newB
+}
+
+// RatingWindow returns the largest rating difference accepted after waiting for wait
+func RatingWindow(wait time.Duration) int {
+	window := ratingWindowBase + int(wait.Seconds()*ratingWindowGrowth)
+	if window > ratingWindowMax {
+		return ratingWindowMax
+	}
+	return window
+}
@@ -0,0 +1,69 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEloUpdate(t *testing.T) {
+	tests := []struct {
+		name         string
+		a, b         int
+		scoreA       float64
+		kA, kB       int
+		wantA, wantB int
+	}{
+		{"equal ratings, win", 1200, 1200, 1, 40, 40, 1220, 1180},
+		{"equal ratings, loss", 1200, 1200, 0, 40, 40, 1180, 1220},
+		{"equal ratings, draw", 1200, 1200, 0.5, 40, 40, 1200, 1200},
+		{"favourite wins", 1400, 1200, 1, 20, 20, 1405, 1195},
+		{"favourite loses", 1400, 1200, 0, 20, 20, 1385, 1215},
+		{"favourite draws", 1400, 1200, 0.5, 20, 20, 1395, 1205},
+		{"provisional underdog wins", 1200, 1400, 1, 40, 20, 1230, 1385},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := EloUpdate(tt.a, tt.b, tt.scoreA, tt.kA, tt.kB)
+			if a != tt.wantA || b != tt.wantB {
+				t.Errorf("EloUpdate() = %d, %d, want %d, %d", a, b, tt.wantA, tt.wantB)
+			}
+		})
+	}
+}
+
+func TestEloK(t *testing.T) {
+	tests := []struct {
+		matches int
+		want    int
+	}{
+		{0, 40},
+		{29, 40},
+		{30, 20},
+		{500, 20},
+	}
+	for _, tt := range tests {
+		if got := EloK(tt.matches); got != tt.want {
+			t.Errorf("EloK(%d) = %d, want %d", tt.matches, got, tt.want)
+		}
+	}
+}
+
+func TestRatingWindow(t *testing.T) {
+	tests := []struct {
+		wait time.Duration
+		want int
+	}{
+		{0, 100},
+		{time.Second, 125},
+		{1500 * time.Millisecond, 137},
+		{10 * time.Second, 350},
+		{35 * time.Second, 975},
+		{36 * time.Second, 1000},
+		{time.Hour, 1000},
+	}
+	for _, tt := range tests {
+		if got := RatingWindow(tt.wait); got != tt.want {
+			t.Errorf("RatingWindow(%s) = %d, want %d", tt.wait, got, tt.want)
+		}
+	}
+}
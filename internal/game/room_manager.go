@@ -1,72 +1,280 @@
 package game
 
 import (
+	"crypto/rand"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
+const (
+	// RoomCodeLength is the length of private room join codes
+	RoomCodeLength = 6
+	// roomCodeAlphabet leaves out 0/O and 1/I so codes are easy to read out;
+	// its 32 letters divide 256 so every letter is equally likely
+	roomCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+	// matchInterval is how often waiting players are re-checked as their rating windows widen
+	matchInterval = time.Second
+)
+
+// RoomManager keeps track of the Sunny Says rooms and matches players into
+// them. Rooms run on their own goroutines and are never called while mu is
+// held; a room removes itself from the manager when it ends.
 type RoomManager struct {
 	rooms map[string]*SunnySaysRoom
+	codes map[string]*SunnySaysRoom // Private rooms by join code
 	mu    sync.RWMutex
+
+	// matchMu serializes matchmaking so two players can't both open a new room for each other
+	matchMu sync.Mutex
+
+	// record stores a finished match; it is called off the room's goroutine
+	record func(MatchResult) MatchRecord
 }
 
-func NewRoomManager() *RoomManager {
+// NewRoomManager creates a room manager. record is called with the result
+// of every finished match, and what it returns is sent to the players with
+// game over.
+func NewRoomManager(record func(MatchResult) MatchRecord) *RoomManager {
 	rm := &RoomManager{
-		rooms: make(map[string]*SunnySaysRoom),
+		rooms:  make(map[string]*SunnySaysRoom),
+		codes:  make(map[string]*SunnySaysRoom),
+		record: record,
 	}
-	
-	// Start cleanup goroutine to remove empty/ended rooms
-	go rm.cleanupRoutine()
-	
+	go rm.matchRoutine()
 	return rm
 }
 
-func (rm *RoomManager) FindOrCreateRoom() *SunnySaysRoom {
+// create starts a room and registers it until it ends. Private rooms get
+// a join code no other room is using.
+func (rm *RoomManager) create(opts roomOptions) *SunnySaysRoom {
+	opts.record = rm.record
 	rm.mu.Lock()
-	defer rm.mu.Unlock()
-	
-	// Find an unfilled room
-	for _, room := range rm.rooms {
-		if !room.IsFull() && room.State == RoomStateWaiting {
-			return room
+	if opts.private {
+		opts.code = newRoomCode()
+		for rm.codes[opts.code] != nil {
+			opts.code = newRoomCode()
 		}
 	}
-	
-	// Create new room if none found
-	room := NewSunnySaysRoom()
+	room := newRoom(opts)
 	rm.rooms[room.ID] = room
+	if room.Code != "" {
+		rm.codes[room.Code] = room
+	}
+	rm.mu.Unlock()
+
+	go func() {
+		<-room.Done()
+		rm.remove(room)
+	}()
 	return room
 }
 
-func (rm *RoomManager) GetRoom(roomID string) *SunnySaysRoom {
+// remove deletes a room that has ended and frees its code
+func (rm *RoomManager) remove(room *SunnySaysRoom) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	if room.Code != "" {
+		delete(rm.codes, room.Code)
+	}
+	delete(rm.rooms, room.ID)
+}
+
+// list returns the rooms, so they can be called without holding rm.mu
+func (rm *RoomManager) list() []*SunnySaysRoom {
 	rm.mu.RLock()
 	defer rm.mu.RUnlock()
-	return rm.rooms[roomID]
+	out := make([]*SunnySaysRoom, 0, len(rm.rooms))
+	for _, room := range rm.rooms {
+		out = append(out, room)
+	}
+	return out
 }
 
-func (rm *RoomManager) RemoveRoom(roomID string) {
-	rm.mu.Lock()
-	defer rm.mu.Unlock()
-	delete(rm.rooms, roomID)
+// Matchmake adds player to the public room of size maxPlayers whose waiting
+// players' average rating is closest to theirs, provided the difference is within
+// the room's rating window, or to a new room if nobody fits. Rooms where
+// the player's user is already waiting are skipped. The room starts
+// its match if the player filled it. It returns nil if the player has
+// disconnected.
+func (rm *RoomManager) Matchmake(player *Player, maxPlayers int) *SunnySaysRoom {
+	rm.matchMu.Lock()
+	defer rm.matchMu.Unlock()
+
+	now := time.Now()
+	var best *SunnySaysRoom
+	bestDiff := 0
+	for _, room := range rm.list() {
+		if room.MaxPlayers != maxPlayers || room.HasUser(player.UserID) {
+			continue
+		}
+		_, rating, ok := room.queued()
+		if !ok {
+			continue
+		}
+		diff := abs(rating - player.Rating)
+		if diff > RatingWindow(now.Sub(room.CreatedAt)) {
+			continue
+		}
+		if best == nil || diff < bestDiff {
+			best, bestDiff = room, diff
+		}
+	}
+	if best != nil && best.Join(player) {
+		return best
+	}
+	return rm.open(roomOptions{maxPlayers: maxPlayers}, player)
 }
 
-func (rm *RoomManager) cleanupRoutine() {
-	ticker := time.NewTicker(30 * time.Second)
+// open creates a room and joins the players to it. It returns nil, and
+// closes the room, if none of them could join.
+func (rm *RoomManager) open(opts roomOptions, players ...*Player) *SunnySaysRoom {
+	room := rm.create(opts)
+	joined := false
+	for _, p := range players {
+		if room.Join(p) {
+			joined = true
+		}
+	}
+	if !joined {
+		room.closeIfEmpty()
+		return nil
+	}
+	return room
+}
+
+func (rm *RoomManager) matchRoutine() {
+	ticker := time.NewTicker(matchInterval)
 	defer ticker.Stop()
-	
+
 	for range ticker.C {
-		rm.mu.Lock()
-		for id, room := range rm.rooms {
-			// Remove rooms that are ended or have been waiting too long (>5 minutes)
-			if room.State == RoomStateEnded {
-				delete(rm.rooms, id)
-			} else if room.State == RoomStateWaiting {
-				if time.Since(room.CreatedAt) > 5*time.Minute {
-					delete(rm.rooms, id)
+		rm.matchWaiting()
+	}
+}
+
+// matchWaiting merges queued rooms of the same size whose rating windows have
+// widened enough, moving the newer room's players into the older room when they
+// all fit and none of their users is already there. Rooms start their match
+// once they are full.
+func (rm *RoomManager) matchWaiting() {
+	rm.matchMu.Lock()
+	defer rm.matchMu.Unlock()
+
+	var queue []*SunnySaysRoom
+	for _, room := range rm.list() {
+		if _, _, ok := room.queued(); ok {
+			queue = append(queue, room)
+		}
+	}
+	sort.Slice(queue, func(i, j int) bool { return queue[i].CreatedAt.Before(queue[j].CreatedAt) })
+
+	now := time.Now()
+	merged := make(map[*SunnySaysRoom]bool)
+	for i, older := range queue {
+		if merged[older] {
+			continue
+		}
+		// The longer-waiting room's window is the wider one
+		window := RatingWindow(now.Sub(older.CreatedAt))
+		for _, newer := range queue[i+1:] {
+			have, rating, ok := older.queued()
+			if !ok {
+				break
+			}
+			moving, newerRating, ok := newer.queued()
+			if merged[newer] || !ok || newer.MaxPlayers != older.MaxPlayers ||
+				have+moving > older.MaxPlayers ||
+				abs(rating-newerRating) > window || sharesUser(older, newer) {
+				continue
+			}
+			merged[newer] = true
+			var left []*Player
+			for _, p := range newer.takeQueued() {
+				if !older.Join(p) {
+					left = append(left, p)
 				}
 			}
+			// Players may have left the older room while being matched
+			if len(left) > 0 {
+				rm.open(roomOptions{maxPlayers: newer.MaxPlayers}, left...)
+			}
+		}
+	}
+}
+
+// sharesUser reports whether a user has a player in both rooms
+func sharesUser(a, b *SunnySaysRoom) bool {
+	for _, id := range b.userIDs() {
+		if a.HasUser(id) {
+			return true
+		}
+	}
+	return false
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// CreatePrivateRoom creates a room for up to maxPlayers that is only joined
+// with its code, hosted by player. It returns nil if the player has disconnected.
+func (rm *RoomManager) CreatePrivateRoom(player *Player, maxPlayers int) *SunnySaysRoom {
+	return rm.open(roomOptions{maxPlayers: maxPlayers, private: true}, player)
+}
+
+// CreateSoloRoom starts a single-player game for player, paid for by the
+// game session sessionID, which left them with energy. It returns nil if
+// the player has disconnected.
+func (rm *RoomManager) CreateSoloRoom(player *Player, sessionID string, energy int) *SunnySaysRoom {
+	return rm.open(roomOptions{maxPlayers: SoloPlayers, sessionID: sessionID, energy: &energy}, player)
+}
+
+// FindPlayer returns the player of a running match with the resume token,
+// or nil if there is none
+func (rm *RoomManager) FindPlayer(resumeToken string) *Player {
+	for _, room := range rm.list() {
+		if p := room.playerByToken(resumeToken); p != nil {
+			return p
 		}
-		rm.mu.Unlock()
 	}
+	return nil
+}
+
+// FindRoomByCode returns the private room with the join code, ignoring case
+func (rm *RoomManager) FindRoomByCode(code string) *SunnySaysRoom {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+	return rm.codes[strings.ToUpper(strings.TrimSpace(code))]
+}
+
+func newRoomCode() string {
+	b := make([]byte, RoomCodeLength)
+	rand.Read(b)
+	for i := range b {
+		b[i] = roomCodeAlphabet[int(b[i])%len(roomCodeAlphabet)]
+	}
+	return string(b)
 }
 
+// LiveRooms describes the public matches in progress, oldest first
+func (rm *RoomManager) LiveRooms() []RoomInfo {
+	out := make([]RoomInfo, 0)
+	for _, room := range rm.list() {
+		if info, ok := room.Info(); ok {
+			out = append(out, info)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StartedAt.Before(out[j].StartedAt) })
+	return out
+}
+
+func (rm *RoomManager) GetRoom(roomID string) *SunnySaysRoom {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+	return rm.rooms[roomID]
+}
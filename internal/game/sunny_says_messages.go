@@ -0,0 +1,88 @@
+package game
+
+import (
+	"time"
+
+	"fsd-backend/internal/pet"
+)
+
+// Messages a Sunny Says room sends to its players and spectators
+const (
+	MsgTypeRoomJoined         = "room_joined"
+	MsgTypeWaiting            = "waiting"
+	MsgTypePlayerJoined       = "player_joined" // Someone joined a private room; the host can start
+	MsgTypeGameStart          = "game_start"
+	MsgTypeRoundStart         = "round_start"
+	MsgTypeSunnyFrame         = "sunny_frame"
+	MsgTypeOpponentFrame      = "opponent_frame"
+	MsgTypePlayerFrame        = "player_frame" // Another player's frame in a battle room
+	MsgTypeRoundResult        = "round_result"
+	MsgTypeGameOver           = "game_over" // Per player, then for the match with its match_id
+	MsgTypeOpponentGameOver   = "opponent_game_over"
+	MsgTypePlayerOut          = "player_out" // A player was eliminated from a battle room
+	MsgTypeScoreboard         = "scoreboard" // Battle standings after each round
+	MsgTypeError              = "error"
+	MsgTypeRematchOffer       = "rematch_offer"       // Game over; players have wait_time to vote on a rematch
+	MsgTypeRematchVoted       = "rematch_voted"       // A player accepted the rematch
+	MsgTypeRematchDeclined    = "rematch_declined"    // No rematch; back to the lobby
+	MsgTypeSpectating         = "spectating"          // Match state sent to a new spectator
+	MsgTypeResumed            = "resumed"             // Match state sent to a player who reconnected
+	MsgTypePlayerDisconnected = "player_disconnected" // A player dropped and may resume within wait_time
+	MsgTypePlayerReconnected  = "player_reconnected"
+)
+
+// Message is sent by the server to a player or spectator
+type Message struct {
+	Type              string          `json:"type"`
+	RoomID            string          `json:"room_id,omitempty"`
+	Code              string          `json:"code,omitempty"`    // Join code of a private room
+	HostID            string          `json:"host_id,omitempty"` // Player who can start a private room
+	Rating            int             `json:"rating,omitempty"`  // The receiving player's matchmaking rating
+	PlayerID          string          `json:"player_id,omitempty"`
+	OpponentID        string          `json:"opponent_id,omitempty"`
+	Frame             int             `json:"frame,omitempty"`
+	SunnyFrame        int             `json:"sunny_frame,omitempty"`
+	Score             int             `json:"score,omitempty"`
+	OpponentScore     int             `json:"opponent_score,omitempty"`
+	Round             int             `json:"round,omitempty"`
+	WaitTime          int             `json:"wait_time,omitempty"` // milliseconds
+	Message           string          `json:"message,omitempty"`
+	DisplayDurationMs int             `json:"display_duration_ms"`    // Duration in milliseconds for client to display this frame (0 = no duration, final frame)
+	Pet               *pet.Appearance `json:"pet,omitempty"`          // The receiving player's own pet
+	OpponentPet       *pet.Appearance `json:"opponent_pet,omitempty"` // The opponent's pet, once known
+	MaxPlayers        int             `json:"max_players,omitempty"`  // Room size
+	Players           []PlayerInfo    `json:"players,omitempty"`      // The other players in the room
+	Scoreboard        []ScoreEntry    `json:"scoreboard,omitempty"`   // Battle standings, best first
+	WinnerID          string          `json:"winner_id,omitempty"`    // Empty for a draw
+	Forfeit           bool            `json:"forfeit,omitempty"`
+	Energy            *int            `json:"energy,omitempty"`       // Energy left after paying for a solo game
+	Result            any             `json:"result,omitempty"`       // The receiving player's rewards for a finished game
+	MatchID           string          `json:"match_id,omitempty"`     // The stored result of a finished match
+	ResumeToken       string          `json:"resume_token,omitempty"` // Pass as resume= to reconnect to the match
+	RoundActive       bool            `json:"round_active,omitempty"`
+}
+
+// PlayerInfo identifies another player in the room
+type PlayerInfo struct {
+	PlayerID string          `json:"player_id"`
+	Pet      *pet.Appearance `json:"pet,omitempty"`
+}
+
+// ScoreEntry is one player's line on a scoreboard
+type ScoreEntry struct {
+	PlayerID string `json:"player_id"`
+	Score    int    `json:"score"`
+	Out      bool   `json:"out"`
+	Forfeit  bool   `json:"forfeit,omitempty"`
+}
+
+// RoomInfo describes a public match in progress
+type RoomInfo struct {
+	RoomID     string       `json:"room_id"`
+	MaxPlayers int          `json:"max_players"`
+	Round      int          `json:"round"`
+	StartedAt  time.Time    `json:"started_at"`
+	Players    []PlayerInfo `json:"players"`
+	Scoreboard []ScoreEntry `json:"scoreboard"`
+	Spectators int          `json:"spectators"`
+}
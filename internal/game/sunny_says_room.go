@@ -1,322 +1,897 @@
 package game
 
 import (
+	"context"
+	"errors"
+	"log"
 	"math/rand"
 	"sync"
 	"time"
 
+	"fsd-backend/internal/pet"
+
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
 const (
-	RoomMaxPlayers = 2
+	SoloPlayers     = 1 // Room size of a single-player game run by the server
+	DuelPlayers     = 2 // Room size of 1v1 matches, the default
+	MinRoomPlayers  = 2
+	MaxRoomPlayers  = 8 // Battle rooms hold 3 up to this many players
 	RoomWaitTimeout = 10 * time.Second
+	ReconnectGrace  = 15 * time.Second // How long a dropped player's slot is kept mid-match
+	RematchTimeout  = 15 * time.Second // How long players have to accept a rematch
+
+	// writeTimeout bounds each WebSocket write so a stalled client can't hold up its room
+	writeTimeout = 2 * time.Second
+	// roomEventBuffer is how many events can queue for a busy room before senders wait
+	roomEventBuffer = 64
+)
+
+var (
+	ErrNotHost          = errors.New("only the host can start the match")
+	ErrNotEnoughPlayers = errors.New("room needs at least two players")
+	ErrAlreadyStarted   = errors.New("match already started")
+)
+
+type RoomState string
+
+const (
+	RoomStateWaiting RoomState = "waiting" // Waiting for players
+	RoomStatePlaying RoomState = "playing" // Game in progress
+	RoomStateSaving  RoomState = "saving"  // Everyone is out; the result is being stored
+	RoomStateRematch RoomState = "rematch" // Game over, players voting on a rematch
+	RoomStateEnded   RoomState = "ended"   // The room's goroutine has stopped
 )
 
+// Clock tells a room the time and runs its timers. Rooms use the system
+// clock; tests use a fake one to play whole matches instantly.
+type Clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func())
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) AfterFunc(d time.Duration, f func()) { time.AfterFunc(d, f) }
+
+// Rand is where a room's random choices come from: Sunny's symbols, the
+// round timing and confusion. *rand.Rand satisfies it. It is only used by
+// the room's goroutine, so it needn't be safe for concurrent use.
+type Rand interface {
+	Intn(n int) int
+	Int63n(n int64) int64
+	Float32() float32
+}
+
+// Player is a connected player. The game fields are owned by the goroutine
+// of the player's room.
 type Player struct {
-	ID       string
-	UserID   string
-	Conn     *websocket.Conn
-	Score    int
-	Frame    int  // Current pet frame (0-3)
-	GameOver bool
-	Ready    bool  // Ready after countdown
-	mu       sync.Mutex
-	writeMu  sync.Mutex // Protects WebSocket writes (must be separate from mu)
+	ID          string
+	UserID      string
+	Pet         *pet.Appearance // How the player's pet looks to others (nil if they have none)
+	Rating      int             // Matchmaking rating when the player joined
+	ResumeToken string          // Secret that lets the player reconnect to the match
+
+	score    int
+	frame    int // Current pet frame (0-3)
+	gameOver bool
+	ready    bool      // Ready after countdown
+	endedAt  time.Time // When the player's game ended
+	forfeit  bool      // Left the match before their game ended
+
+	mu      sync.Mutex // Protects conn, room and gone
+	conn    *websocket.Conn
+	room    *SunnySaysRoom // Room the player is in; matchmaking can move a waiting player
+	gone    bool           // The player's connection closed; rooms won't take them
+	writeMu sync.Mutex     // Serializes writes to conn
 }
 
 func NewPlayer(userID string, conn *websocket.Conn) *Player {
 	return &Player{
-		ID:       uuid.New().String(),
-		UserID:   userID,
-		Conn:     conn,
-		Score:    0,
-		Frame:    0,
-		GameOver: false,
-		Ready:    false,
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		ResumeToken: uuid.New().String(),
+		conn:        conn,
 	}
 }
 
-func (p *Player) SetReady(ready bool) {
+// Connection returns the player's current WebSocket connection
+func (p *Player) Connection() *websocket.Conn {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	p.Ready = ready
+	return p.conn
 }
 
-func (p *Player) IsReady() bool {
+// Room returns the room the player is currently in
+func (p *Player) Room() *SunnySaysRoom {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	return p.Ready
+	return p.room
 }
 
-func (p *Player) SetFrame(frame int) {
+// Disconnect tells the player's room that conn closed. Mid-match the player
+// keeps their slot for ReconnectGrace; otherwise they leave the room.
+func (p *Player) Disconnect(conn *websocket.Conn) {
 	p.mu.Lock()
-	defer p.mu.Unlock()
-	p.Frame = frame
-}
+	if p.conn == conn {
+		p.gone = true
+	}
+	room := p.room
+	p.mu.Unlock()
 
-func (p *Player) GetFrame() int {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	return p.Frame
+	if room != nil {
+		room.post(func() { room.disconnect(p, conn) })
+	}
 }
 
-func (p *Player) SetGameOver() {
+// enter moves the player into r unless their connection has closed
+func (p *Player) enter(r *SunnySaysRoom) bool {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	p.GameOver = true
+	if p.gone {
+		return false
+	}
+	p.room = r
+	return true
 }
 
-func (p *Player) IsGameOver() bool {
+// reconnect moves the player to a new connection and returns the old one
+func (p *Player) reconnect(conn *websocket.Conn) *websocket.Conn {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	return p.GameOver
+	old := p.conn
+	p.conn = conn
+	p.gone = false
+	return old
 }
 
-// SendMessage sends a message through the WebSocket connection with mutex protection
-// This ensures thread-safe writes to the connection
-func (p *Player) SendMessage(msg interface{}) error {
+// Send writes msg to the player's connection. A connection that can't be
+// written to is closed, which disconnects the player.
+func (p *Player) Send(msg Message) {
 	p.writeMu.Lock()
 	defer p.writeMu.Unlock()
-	return p.Conn.WriteJSON(msg)
+	send(p.Connection(), msg)
 }
 
-type RoomState string
+// reset clears the player's result for a rematch
+func (p *Player) reset() {
+	p.score = 0
+	p.frame = 0
+	p.gameOver = false
+	p.ready = false
+	p.endedAt = time.Time{}
+	p.forfeit = false
+}
 
-const (
-	RoomStateWaiting RoomState = "waiting"  // Waiting for second player
-	RoomStatePlaying RoomState = "playing"  // Game in progress
-	RoomStateEnded   RoomState = "ended"     // Both players game over
-)
+func (p *Player) setGameOver(now time.Time) {
+	if !p.gameOver {
+		p.endedAt = now
+	}
+	p.gameOver = true
+}
+
+// Spectator watches a public match without taking part
+type Spectator struct {
+	ID      string
+	UserID  string
+	Conn    *websocket.Conn
+	writeMu sync.Mutex
+}
+
+func NewSpectator(userID string, conn *websocket.Conn) *Spectator {
+	return &Spectator{ID: uuid.New().String(), UserID: userID, Conn: conn}
+}
+
+// Send writes msg to the spectator's connection
+func (s *Spectator) Send(msg Message) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	send(s.Conn, msg)
+}
+
+func send(conn *websocket.Conn, msg Message) {
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := conn.WriteJSON(msg); err != nil {
+		log.Printf("Error sending %s message: %v", msg.Type, err)
+		conn.Close()
+	}
+}
 
+// SunnySaysRoom is one Sunny Says room. Its state is owned by a single
+// goroutine that runs the room's events one at a time, so nothing but the
+// fields set at creation is read or written from outside. The goroutine
+// stops when the room ends.
 type SunnySaysRoom struct {
-	ID        string
-	Players   []*Player
-	State     RoomState
-	CreatedAt time.Time
-	mu        sync.RWMutex
-	
+	// Set at creation
+	ID         string
+	MaxPlayers int // SoloPlayers, DuelPlayers for 1v1, more for a battle room
+	CreatedAt  time.Time
+	Private    bool // Joined by Code and started by the host instead of matchmaking
+	Code       string
+	SessionID  string // The game session that paid for a solo game
+	energy     *int   // Energy left after paying for a solo game
+
+	record func(MatchResult) MatchRecord
+	clock  Clock
+	rand   Rand
+	events chan func()
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Owned by the room's goroutine
+	state     RoomState
+	players   []*Player
+	hostID    string    // The first player to join, then the longest-waiting one
+	startedAt time.Time // When the match began
+
+	// Everyone who took part in the match, including players who have since left
+	participants []*Player
+
+	// Spectators watch the match; they never count as players
+	spectators []*Spectator
+
+	// Rematch votes by player ID while state is RoomStateRematch
+	rematchVotes map[string]bool
+
 	// Game state
-	CurrentRound      int
-	SunnyFrame        int  // Current Sunny's frame (0-3)
-	RoundActive       bool
-	ConfusionEnabled  bool
-	WaitStartTime     time.Time
-	SunnyShowTime     time.Time
-	muGame            sync.RWMutex
-}
-
-func NewSunnySaysRoom() *SunnySaysRoom {
-	return &SunnySaysRoom{
-		ID:        uuid.New().String(),
-		Players:   make([]*Player, 0, RoomMaxPlayers),
-		State:     RoomStateWaiting,
-		CreatedAt: time.Now(),
-		CurrentRound: 0,
-		SunnyFrame: 0,
-		RoundActive: false,
-		ConfusionEnabled: false,
-	}
-}
-
-func (r *SunnySaysRoom) AddPlayer(player *Player) bool {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	
-	if len(r.Players) >= RoomMaxPlayers {
-		return false
+	match            int // Counts matches started, so timers of an earlier one are ignored
+	round            int
+	sunnyFrame       int // Current Sunny's frame (0-3)
+	symbol           int // The symbol Sunny ends the round on, chosen when it starts
+	roundActive      bool
+	confusionEnabled bool
+	flashesLeft      int // Confusion flashes still to show this round
+}
+
+// roomOptions are the settings of a new room
+type roomOptions struct {
+	maxPlayers int
+	private    bool
+	code       string
+	sessionID  string
+	energy     *int
+	record     func(MatchResult) MatchRecord
+	clock      Clock // The system clock if nil
+	rand       Rand  // Seeded from the time if nil
+}
+
+// newRoom creates a room and starts its goroutine
+func newRoom(opts roomOptions) *SunnySaysRoom {
+	if opts.clock == nil {
+		opts.clock = systemClock{}
 	}
-	
-	r.Players = append(r.Players, player)
-	
-	if len(r.Players) == RoomMaxPlayers {
-		r.State = RoomStatePlaying
+	if opts.rand == nil {
+		opts.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
 	}
-	
-	return true
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &SunnySaysRoom{
+		ID:         uuid.New().String(),
+		MaxPlayers: opts.maxPlayers,
+		CreatedAt:  opts.clock.Now(),
+		Private:    opts.private,
+		Code:       opts.code,
+		SessionID:  opts.sessionID,
+		energy:     opts.energy,
+		record:     opts.record,
+		clock:      opts.clock,
+		rand:       opts.rand,
+		events:     make(chan func(), roomEventBuffer),
+		ctx:        ctx,
+		cancel:     cancel,
+		state:      RoomStateWaiting,
+		players:    make([]*Player, 0, opts.maxPlayers),
+	}
+	go r.run()
+	return r
 }
 
-func (r *SunnySaysRoom) RemovePlayer(playerID string) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	
-	for i, p := range r.Players {
-		if p.ID == playerID {
-			r.Players = append(r.Players[:i], r.Players[i+1:]...)
-			break
+// run is the room's goroutine
+func (r *SunnySaysRoom) run() {
+	defer r.cancel()
+	for {
+		select {
+		case f := <-r.events:
+			f()
+			if r.state == RoomStateEnded {
+				return
+			}
+		case <-r.ctx.Done():
+			return
 		}
 	}
-	
-	if len(r.Players) == 0 {
-		r.State = RoomStateEnded
+}
+
+// post queues f to run on the room's goroutine. It reports false, without
+// running f, if the room has ended.
+func (r *SunnySaysRoom) post(f func()) bool {
+	select {
+	case r.events <- f:
+		return true
+	case <-r.ctx.Done():
+		return false
 	}
 }
 
-func (r *SunnySaysRoom) GetPlayer(playerID string) *Player {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-	
-	for _, p := range r.Players {
-		if p.ID == playerID {
-			return p
+// call runs f on the room's goroutine and waits for it. It reports false if
+// the room ended before f ran.
+func (r *SunnySaysRoom) call(f func()) bool {
+	done := make(chan struct{})
+	if !r.post(func() { f(); close(done) }) {
+		return false
+	}
+	select {
+	case <-done:
+		return true
+	case <-r.ctx.Done():
+		// The room may have ended right after running f
+		select {
+		case <-done:
+			return true
+		default:
+			return false
 		}
 	}
-	return nil
 }
 
-func (r *SunnySaysRoom) GetOpponent(playerID string) *Player {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-	
-	for _, p := range r.Players {
-		if p.ID != playerID {
-			return p
+// after runs f on the room's goroutine once d has passed, unless the room has ended by then
+func (r *SunnySaysRoom) after(d time.Duration, f func()) {
+	r.clock.AfterFunc(d, func() { r.post(f) })
+}
+
+// Done is closed when the room has ended
+func (r *SunnySaysRoom) Done() <-chan struct{} {
+	return r.ctx.Done()
+}
+
+// end stops the room's goroutine once the current event is done
+func (r *SunnySaysRoom) end() {
+	r.state = RoomStateEnded
+}
+
+// IsSolo reports whether the room is a single-player game
+func (r *SunnySaysRoom) IsSolo() bool {
+	return r.MaxPlayers == SoloPlayers
+}
+
+// IsBattle reports whether the room is a battle for more than two players,
+// won by the last player standing
+func (r *SunnySaysRoom) IsBattle() bool {
+	return r.MaxPlayers > DuelPlayers
+}
+
+// Join adds player to the room and tells everyone. A public room starts
+// its match as soon as it is full; a private one waits for the host.
+// It reports false if the room is full, its match has started or the
+// player's user is already in it.
+func (r *SunnySaysRoom) Join(player *Player) bool {
+	joined := false
+	r.call(func() { joined = r.join(player) })
+	return joined
+}
+
+// HasUser reports whether one of the room's players belongs to userID
+func (r *SunnySaysRoom) HasUser(userID string) bool {
+	found := false
+	r.call(func() { found = r.hasUser(userID) })
+	return found
+}
+
+// LeaveQueue takes a waiting player out of the room, e.g. to play solo.
+// It reports false if the player isn't waiting in this room.
+func (r *SunnySaysRoom) LeaveQueue(player *Player) bool {
+	left := false
+	r.call(func() {
+		if r.state == RoomStateWaiting && r.has(player) {
+			r.leave(player)
+			left = true
 		}
-	}
-	return nil
+	})
+	return left
 }
 
-func (r *SunnySaysRoom) IsFull() bool {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-	return len(r.Players) >= RoomMaxPlayers
+// KeepWaiting tells a waiting player they're still queued and asks them again after RoomWaitTimeout
+func (r *SunnySaysRoom) KeepWaiting(player *Player) {
+	r.post(func() {
+		if r.state == RoomStateWaiting && r.has(player) {
+			player.Send(Message{Type: MsgTypeWaiting})
+			r.waitTimeout(player)
+		}
+	})
 }
 
-func (r *SunnySaysRoom) AllPlayersGameOver() bool {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-	
-	// If no players, all are game over (room should be cleaned up)
-	if len(r.Players) == 0 {
-		return true
-	}
-	
-	// Check if all remaining players are game over
-	for _, p := range r.Players {
-		if !p.IsGameOver() {
-			return false
+// Start begins a room's match on the host's request.
+// Battle rooms can start before they are full.
+func (r *SunnySaysRoom) Start(player *Player) {
+	r.post(func() {
+		if err := r.startByHost(player); err != nil {
+			player.Send(Message{Type: MsgTypeError, Message: startError(err)})
 		}
-	}
-	return true
+	})
 }
 
-func (r *SunnySaysRoom) AllPlayersReady() bool {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-	
-	// Once game has started (State == Playing), only need active (non-game-over) players to be ready
-	// Before game starts, need all players (for initial countdown)
-	if r.State == RoomStatePlaying {
-		// Game has started - only need active players to be ready
-		hasActivePlayers := false
-		for _, p := range r.Players {
-			if !p.IsGameOver() {
-				hasActivePlayers = true
-				if !p.IsReady() {
-					return false
-				}
+// Input records the frame the player is showing and relays it
+func (r *SunnySaysRoom) Input(player *Player, frame int) {
+	r.post(func() { r.input(player, frame) })
+}
+
+// Ready marks the player ready for the next round, which starts once every
+// player still in the game is ready
+func (r *SunnySaysRoom) Ready(player *Player) {
+	r.post(func() { r.setReady(player) })
+}
+
+// VoteRematch records the player's rematch vote. A decline ends the vote straight away.
+func (r *SunnySaysRoom) VoteRematch(player *Player, accept bool) {
+	r.post(func() { r.voteRematch(player, accept) })
+}
+
+// Resume moves a player who dropped out of the running match to conn and
+// sends them the match state. It reports false if they can't resume.
+func (r *SunnySaysRoom) Resume(player *Player, conn *websocket.Conn, userID string) bool {
+	resumed := false
+	r.call(func() { resumed = r.resume(player, conn, userID) })
+	return resumed
+}
+
+// AddSpectator lets s watch the room and sends them the match state.
+// Only public matches in progress can be watched.
+func (r *SunnySaysRoom) AddSpectator(s *Spectator) bool {
+	added := false
+	r.call(func() {
+		if r.Private || r.IsSolo() || r.state != RoomStatePlaying {
+			return
+		}
+		r.spectators = append(r.spectators, s)
+		msg := Message{
+			Type:        MsgTypeSpectating,
+			RoomID:      r.ID,
+			MaxPlayers:  r.MaxPlayers,
+			Players:     playerInfos(r.participants),
+			Round:       r.round,
+			RoundActive: r.roundActive,
+			Scoreboard:  r.scoreboard(),
+		}
+		if r.roundActive {
+			msg.SunnyFrame = r.sunnyFrame
+		}
+		s.Send(msg)
+		added = true
+	})
+	return added
+}
+
+func (r *SunnySaysRoom) RemoveSpectator(s *Spectator) {
+	r.post(func() {
+		for i, o := range r.spectators {
+			if o == s {
+				r.spectators = append(r.spectators[:i], r.spectators[i+1:]...)
+				return
 			}
 		}
-		return hasActivePlayers // Return true if at least one active player is ready
-	} else {
-		// Game hasn't started yet - need all players to be ready
-		if len(r.Players) < RoomMaxPlayers {
-			return false
+	})
+}
+
+// Info describes the room if it is a public match in progress
+func (r *SunnySaysRoom) Info() (RoomInfo, bool) {
+	var info RoomInfo
+	live := false
+	r.call(func() {
+		if r.Private || r.IsSolo() || r.state != RoomStatePlaying {
+			return
+		}
+		info = RoomInfo{
+			RoomID:     r.ID,
+			MaxPlayers: r.MaxPlayers,
+			Round:      r.round,
+			StartedAt:  r.startedAt,
+			Players:    playerInfos(r.participants),
+			Scoreboard: r.scoreboard(),
+			Spectators: len(r.spectators),
+		}
+		live = true
+	})
+	return info, live
+}
+
+// playerByToken returns the player of the running match with the resume token
+func (r *SunnySaysRoom) playerByToken(resumeToken string) *Player {
+	var found *Player
+	r.call(func() {
+		if r.state != RoomStatePlaying {
+			return
 		}
-		
-		for _, p := range r.Players {
-			if !p.IsReady() {
-				return false
+		for _, p := range r.players {
+			if p.ResumeToken == resumeToken {
+				found = p
 			}
 		}
-		return true
+	})
+	return found
+}
+
+// queued returns how many players of a public room wait in the matchmaking
+// queue and their average rating; ok is false if the room isn't queued
+func (r *SunnySaysRoom) queued() (count, rating int, ok bool) {
+	r.call(func() {
+		if r.Private || r.state != RoomStateWaiting || len(r.players) == 0 || len(r.players) >= r.MaxPlayers {
+			return
+		}
+		total := 0
+		for _, p := range r.players {
+			total += p.Rating
+		}
+		count, rating, ok = len(r.players), total/len(r.players), true
+	})
+	return count, rating, ok
+}
+
+// takeQueued ends a queued room and returns its players so matchmaking can
+// move them to another room. It returns nil if the room isn't queued.
+func (r *SunnySaysRoom) takeQueued() []*Player {
+	var players []*Player
+	r.call(func() {
+		if r.Private || r.state != RoomStateWaiting || len(r.players) >= r.MaxPlayers {
+			return
+		}
+		players = r.players
+		r.players = nil
+		r.end()
+	})
+	return players
+}
+
+// closeIfEmpty ends a room nobody joined
+func (r *SunnySaysRoom) closeIfEmpty() {
+	r.post(func() {
+		if len(r.players) == 0 {
+			r.end()
+		}
+	})
+}
+
+func (r *SunnySaysRoom) has(player *Player) bool {
+	for _, p := range r.players {
+		if p == player {
+			return true
+		}
 	}
+	return false
 }
 
-func (r *SunnySaysRoom) ResetReady() {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	
-	for _, p := range r.Players {
-		p.SetReady(false)
+func (r *SunnySaysRoom) hasUser(userID string) bool {
+	for _, p := range r.players {
+		if p.UserID == userID {
+			return true
+		}
 	}
+	return false
 }
 
-// Sunny AI Logic (moved from Godot)
+// userIDs returns the users of the room's players
+func (r *SunnySaysRoom) userIDs() []string {
+	var ids []string
+	r.call(func() {
+		for _, p := range r.players {
+			ids = append(ids, p.UserID)
+		}
+	})
+	return ids
+}
 
-func (r *SunnySaysRoom) ChooseRandomSymbol() int {
-	// Returns random frame: 1 (heart), 2 (diamond), or 3 (both)
-	// Frame 0 (nothing) is not used as Sunny's choice
-	return rand.Intn(3) + 1
+func (r *SunnySaysRoom) isFull() bool {
+	return len(r.players) >= r.MaxPlayers
 }
 
-func (r *SunnySaysRoom) ShouldUseConfusion() bool {
-	r.muGame.RLock()
-	defer r.muGame.RUnlock()
-	
-	// Check if any player has score >= 3
-	for _, p := range r.Players {
-		if p.Score >= 3 {
-			// Random chance to use confusion (50% chance)
-			return rand.Float32() < 0.5
+func (r *SunnySaysRoom) join(player *Player) bool {
+	// A user can't play against themselves from a second connection
+	if r.state != RoomStateWaiting || r.isFull() || r.hasUser(player.UserID) || !player.enter(r) {
+		return false
+	}
+	r.players = append(r.players, player)
+	if r.hostID == "" {
+		r.hostID = player.ID
+	}
+
+	// Tell the player about the room, including the pets of anyone already waiting
+	others := otherPlayers(r.players, player)
+	joined := Message{
+		Type:       MsgTypeRoomJoined,
+		RoomID:     r.ID,
+		PlayerID:   player.ID,
+		Code:       r.Code,
+		HostID:     r.hostID,
+		Rating:     player.Rating,
+		Pet:        player.Pet,
+		MaxPlayers: r.MaxPlayers,
+		Players:    playerInfos(others),
+		Energy:     r.energy,
+	}
+	if !r.IsBattle() && len(others) > 0 {
+		joined.OpponentID = others[0].ID
+		joined.OpponentPet = others[0].Pet
+	}
+	player.Send(joined)
+
+	switch {
+	case r.Private:
+		// Tell everyone already in the room who joined; the host starts the match
+		for _, p := range others {
+			msg := Message{
+				Type:    MsgTypePlayerJoined,
+				HostID:  r.hostID,
+				Players: playerInfos([]*Player{player}),
+			}
+			if !r.IsBattle() {
+				msg.OpponentID = player.ID
+				msg.OpponentPet = player.Pet
+			}
+			p.Send(msg)
+		}
+		// Private rooms wait for invited friends without the singleplayer prompt
+		if !r.isFull() {
+			player.Send(Message{Type: MsgTypeWaiting, Code: r.Code})
 		}
+	case r.isFull():
+		r.startMatch()
+	default:
+		player.Send(Message{Type: MsgTypeWaiting})
+		r.waitTimeout(player)
 	}
-	return false
+	return true
+}
+
+// waitTimeout asks a waiting player to keep waiting or play solo once
+// RoomWaitTimeout has passed without the room filling up
+func (r *SunnySaysRoom) waitTimeout(player *Player) {
+	r.after(RoomWaitTimeout, func() {
+		if r.state == RoomStateWaiting && !r.isFull() && r.has(player) {
+			player.Send(Message{Type: MsgTypeWaiting, Message: "timeout"})
+		}
+	})
 }
 
-func (r *SunnySaysRoom) GetConfusionSequence() []int {
-	// Flash symbols up to 3 times
-	flashCount := rand.Intn(3) + 1
-	sequence := make([]int, 0, flashCount+1)
-	
-	for i := 0; i < flashCount; i++ {
-		sequence = append(sequence, r.ChooseRandomSymbol())
+func (r *SunnySaysRoom) startByHost(player *Player) error {
+	switch {
+	case r.hostID != player.ID:
+		return ErrNotHost
+	case r.state != RoomStateWaiting:
+		return ErrAlreadyStarted
+	case len(r.players) < MinRoomPlayers:
+		return ErrNotEnoughPlayers
 	}
-	
-	// Final symbol
-	sequence = append(sequence, r.ChooseRandomSymbol())
-	
-	return sequence
+	r.startMatch()
+	return nil
 }
 
-func (r *SunnySaysRoom) SetSunnyFrame(frame int) {
-	r.muGame.Lock()
-	defer r.muGame.Unlock()
-	r.SunnyFrame = frame
+// startError maps a failed host start to the error message sent to the client
+func startError(err error) string {
+	switch err {
+	case ErrNotHost:
+		return "not_host"
+	case ErrNotEnoughPlayers:
+		return "not_enough_players"
+	}
+	return "already_started"
 }
 
-func (r *SunnySaysRoom) GetSunnyFrame() int {
-	r.muGame.RLock()
-	defer r.muGame.RUnlock()
-	return r.SunnyFrame
+// startMatch moves the room to playing and tells every player. The first
+// round starts when they are all ready after their countdown.
+func (r *SunnySaysRoom) startMatch() {
+	r.state = RoomStatePlaying
+	r.startedAt = r.clock.Now()
+	r.participants = append([]*Player(nil), r.players...)
+	r.match++
+
+	for _, p := range r.participants {
+		others := otherPlayers(r.participants, p)
+		msg := Message{
+			Type:        MsgTypeGameStart,
+			ResumeToken: p.ResumeToken,
+			Pet:         p.Pet,
+			MaxPlayers:  r.MaxPlayers,
+			Players:     playerInfos(others),
+		}
+		if !r.IsBattle() && len(others) > 0 {
+			msg.OpponentID = others[0].ID
+			msg.OpponentPet = others[0].Pet
+		}
+		p.Send(msg)
+	}
 }
 
-func (r *SunnySaysRoom) SetRoundActive(active bool) {
-	r.muGame.Lock()
-	defer r.muGame.Unlock()
-	r.RoundActive = active
+func (r *SunnySaysRoom) input(player *Player, frame int) {
+	if r.state != RoomStatePlaying || !r.has(player) {
+		return
+	}
+	player.frame = frame
+
+	// Spectators see every player's frame
+	r.spectate(Message{Type: MsgTypePlayerFrame, PlayerID: player.ID, Frame: frame})
+
+	// Relay it to the other players still in the game
+	for _, p := range otherPlayers(r.players, player) {
+		if p.gameOver {
+			continue
+		}
+		if r.IsBattle() {
+			p.Send(Message{Type: MsgTypePlayerFrame, PlayerID: player.ID, Frame: frame})
+		} else {
+			p.Send(Message{Type: MsgTypeOpponentFrame, Frame: frame})
+		}
+	}
 }
 
-func (r *SunnySaysRoom) IsRoundActive() bool {
-	r.muGame.RLock()
-	defer r.muGame.RUnlock()
-	return r.RoundActive
+// disconnect handles a player's closed connection. Mid-match they keep
+// their slot until ReconnectGrace has passed, unless they resume.
+func (r *SunnySaysRoom) disconnect(player *Player, conn *websocket.Conn) {
+	// Nothing to do if they already resumed on a new connection
+	if !r.has(player) || player.Connection() != conn {
+		return
+	}
+	if r.state != RoomStatePlaying || player.gameOver {
+		r.leave(player)
+		return
+	}
+
+	for _, p := range otherPlayers(r.players, player) {
+		p.Send(Message{
+			Type:     MsgTypePlayerDisconnected,
+			PlayerID: player.ID,
+			WaitTime: int(ReconnectGrace.Milliseconds()),
+		})
+	}
+	r.after(ReconnectGrace, func() {
+		if r.has(player) && player.Connection() == conn {
+			r.leave(player)
+		}
+	})
 }
 
-func (r *SunnySaysRoom) IncrementRound() {
-	r.muGame.Lock()
-	defer r.muGame.Unlock()
-	r.CurrentRound++
-	
-	// Enable confusion after any player reaches 3 points
-	if !r.ConfusionEnabled {
-		for _, p := range r.Players {
-			if p.Score >= 3 {
-				r.ConfusionEnabled = true
-				break
+// leave removes a player from the room and tells the others. Leaving
+// mid-match ends the player's game as a forfeit.
+func (r *SunnySaysRoom) leave(player *Player) {
+	if !r.has(player) {
+		return
+	}
+	playing := r.state == RoomStatePlaying
+	forfeit := playing && !player.gameOver
+	if forfeit {
+		player.setGameOver(r.clock.Now())
+		player.forfeit = true
+	}
+	for i, p := range r.players {
+		if p == player {
+			r.players = append(r.players[:i], r.players[i+1:]...)
+			break
+		}
+	}
+	if r.hostID == player.ID && len(r.players) > 0 {
+		r.hostID = r.players[0].ID
+	}
+
+	switch {
+	case playing:
+		if forfeit {
+			out := Message{Type: MsgTypePlayerOut, PlayerID: player.ID, Score: player.score, Forfeit: true}
+			r.spectate(out)
+			if r.IsBattle() {
+				// Battle rooms play on; the last one standing wins
+				r.broadcast(out)
+			} else if opponent := r.opponent(player); opponent != nil && !opponent.gameOver {
+				// The opponent can keep playing on their own
+				opponent.Send(Message{Type: MsgTypeOpponentGameOver})
 			}
 		}
+		if winner := r.lastStanding(); winner != nil {
+			winner.setGameOver(r.clock.Now())
+		}
+		r.checkGameOver()
+		// The others may all be waiting on the player who left to be ready
+		r.maybeStartRound()
+
+	case r.state == RoomStateRematch:
+		// A rematch needs everyone who played
+		r.declineRematch()
+
+	case r.state == RoomStateWaiting:
+		if len(r.players) == 0 {
+			r.end()
+			return
+		}
+		// Notify everyone left, who may now host a private room
+		r.broadcast(Message{
+			Type:     MsgTypeError,
+			Message:  "opponent_disconnected",
+			PlayerID: player.ID,
+			HostID:   r.hostID,
+		})
+
+	case r.state == RoomStateSaving:
+		// finishMatch ends the room if nobody is left
 	}
 }
 
+func (r *SunnySaysRoom) resume(player *Player, conn *websocket.Conn, userID string) bool {
+	if r.state != RoomStatePlaying || !r.has(player) || player.UserID != userID || player.gameOver {
+		return false
+	}
+	// Closing the old connection ends its read loop if it's still open
+	player.reconnect(conn).Close()
+
+	others := otherPlayers(r.participants, player)
+	msg := Message{
+		Type:        MsgTypeResumed,
+		RoomID:      r.ID,
+		PlayerID:    player.ID,
+		ResumeToken: player.ResumeToken,
+		Pet:         player.Pet,
+		MaxPlayers:  r.MaxPlayers,
+		Players:     playerInfos(others),
+		Round:       r.round,
+		Score:       player.score,
+		RoundActive: r.roundActive,
+	}
+	if r.roundActive {
+		msg.SunnyFrame = r.sunnyFrame
+	}
+	if r.IsBattle() {
+		msg.Scoreboard = r.scoreboard()
+	} else if len(others) > 0 {
+		msg.OpponentID = others[0].ID
+		msg.OpponentPet = others[0].Pet
+		msg.OpponentScore = others[0].score
+	}
+	player.Send(msg)
+
+	for _, p := range otherPlayers(r.players, player) {
+		p.Send(Message{Type: MsgTypePlayerReconnected, PlayerID: player.ID})
+	}
+	return true
+}
+
+// opponent returns the other player in a 1v1 room, if they're still here
+func (r *SunnySaysRoom) opponent(player *Player) *Player {
+	for _, p := range r.players {
+		if p != player {
+			return p
+		}
+	}
+	return nil
+}
+
+// broadcast sends msg to every player still in the room
+func (r *SunnySaysRoom) broadcast(msg Message) {
+	for _, p := range r.players {
+		p.Send(msg)
+	}
+}
+
+// spectate sends msg to everyone watching the room
+func (r *SunnySaysRoom) spectate(msg Message) {
+	for _, s := range r.spectators {
+		s.Send(msg)
+	}
+}
+
+// otherPlayers returns the players other than player
+func otherPlayers(players []*Player, player *Player) []*Player {
+	out := make([]*Player, 0, len(players))
+	for _, p := range players {
+		if p != player {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func playerInfos(players []*Player) []PlayerInfo {
+	out := make([]PlayerInfo, 0, len(players))
+	for _, p := range players {
+		out = append(out, PlayerInfo{PlayerID: p.ID, Pet: p.Pet})
+	}
+	return out
+}
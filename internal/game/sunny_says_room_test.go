@@ -0,0 +1,481 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeClock only runs timers when a test fires them, so matches play out
+// instantly and in the same order every time
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	seq    int
+	timers []fakeTimer
+}
+
+type fakeTimer struct {
+	at  time.Time
+	seq int // Timers due at the same time fire in the order they were set
+	f   func()
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	c.timers = append(c.timers, fakeTimer{at: c.now.Add(d), seq: c.seq, f: f})
+}
+
+// fireNext moves the clock to the earliest timer and runs it.
+// It reports false if no timer is set.
+func (c *fakeClock) fireNext() bool {
+	c.mu.Lock()
+	if len(c.timers) == 0 {
+		c.mu.Unlock()
+		return false
+	}
+	next := 0
+	for i, t := range c.timers {
+		if t.at.Before(c.timers[next].at) || (t.at.Equal(c.timers[next].at) && t.seq < c.timers[next].seq) {
+			next = i
+		}
+	}
+	t := c.timers[next]
+	c.timers = append(c.timers[:next], c.timers[next+1:]...)
+	if t.at.After(c.now) {
+		c.now = t.at
+	}
+	c.mu.Unlock()
+
+	t.f()
+	return true
+}
+
+// roundLog is what a test saw of one round as it started
+type roundLog struct {
+	confusion bool
+	flashes   int
+}
+
+// testMatch plays a match in a room with a fake clock and a seeded random
+// source. Players are scripted: each matches Sunny for a number of rounds,
+// then misses.
+type testMatch struct {
+	t       *testing.T
+	room    *SunnySaysRoom
+	clock   *fakeClock
+	players []*Player
+	gone    map[*Player]bool // Players who left or dropped
+	results chan MatchResult
+	rounds  []roundLog
+}
+
+func newTestMatch(t *testing.T, size int, seed int64) *testMatch {
+	t.Helper()
+	m := &testMatch{
+		t:       t,
+		clock:   &fakeClock{now: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)},
+		gone:    make(map[*Player]bool),
+		results: make(chan MatchResult, 1),
+	}
+	m.room = newRoom(roomOptions{
+		maxPlayers: size,
+		clock:      m.clock,
+		rand:       rand.New(rand.NewSource(seed)),
+		record: func(res MatchResult) MatchRecord {
+			m.results <- res
+			return MatchRecord{}
+		},
+	})
+	t.Cleanup(m.room.cancel)
+
+	for i := 0; i < size; i++ {
+		p := NewPlayer(fmt.Sprintf("user-%d", i), testConn(t))
+		if !m.room.Join(p) {
+			t.Fatalf("player %d could not join", i)
+		}
+		m.players = append(m.players, p)
+	}
+	return m
+}
+
+// testConn returns the server end of a WebSocket whose client end discards
+// everything it is sent
+func testConn(t *testing.T) *websocket.Conn {
+	t.Helper()
+	conns := make(chan *websocket.Conn, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		conns <- conn
+	}))
+	t.Cleanup(srv.Close)
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	go func() {
+		for {
+			if _, _, err := client.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	return <-conns
+}
+
+// inspect runs f on the room's goroutine once everything queued before it has run
+func (m *testMatch) inspect(f func()) {
+	m.t.Helper()
+	if !m.room.call(f) {
+		m.t.Fatal("room ended")
+	}
+}
+
+// state reports whether the match is running and a round is active. A
+// room that has already ended after its match is not playing.
+func (m *testMatch) state() (playing, roundActive bool) {
+	m.room.call(func() {
+		playing = m.room.state == RoomStatePlaying
+		roundActive = m.room.roundActive
+	})
+	return playing, roundActive
+}
+
+// runUntil fires timers until done reports true. It reports false if the
+// match ended first.
+func (m *testMatch) runUntil(done func(roundActive bool) bool) bool {
+	m.t.Helper()
+	for {
+		playing, roundActive := m.state()
+		if !playing {
+			return false
+		}
+		if done(roundActive) {
+			return true
+		}
+		if !m.clock.fireNext() {
+			m.t.Fatal("match stalled with no timer set")
+		}
+	}
+}
+
+// play runs the match to its end. hits is how many rounds each player
+// matches before missing; leaves and drops give the round before which a
+// player leaves or loses their connection.
+func (m *testMatch) play(hits []int, leaves, drops map[int]int) MatchResult {
+	m.t.Helper()
+	for round := 1; ; round++ {
+		for i, p := range m.players {
+			switch {
+			case leaves[i] == round:
+				m.inspect(func() { m.room.leave(p) })
+				m.gone[p] = true
+			case drops[i] == round:
+				p.Disconnect(p.Connection())
+				m.gone[p] = true
+			}
+		}
+		for _, p := range m.players {
+			if !m.gone[p] {
+				m.room.Ready(p)
+			}
+		}
+		// Dropped players hold up the round until their grace period is over
+		if !m.runUntil(func(roundActive bool) bool { return roundActive }) {
+			break
+		}
+
+		var symbol int
+		var log roundLog
+		m.inspect(func() {
+			symbol = m.room.symbol
+			log = roundLog{confusion: m.room.confusionEnabled, flashes: m.room.flashesLeft}
+		})
+		m.rounds = append(m.rounds, log)
+		for i, p := range m.players {
+			if m.gone[p] {
+				continue
+			}
+			frame := symbol%3 + 1 // Any other symbol
+			if round <= hits[i] {
+				frame = symbol
+			}
+			m.room.Input(p, frame)
+		}
+		if !m.runUntil(func(roundActive bool) bool { return !roundActive }) {
+			break
+		}
+	}
+
+	select {
+	case res := <-m.results:
+		return res
+	case <-time.After(5 * time.Second):
+		m.t.Fatal("match result was not recorded")
+	}
+	return MatchResult{}
+}
+
+type matchTest struct {
+	name     string
+	size     int
+	hits     []int       // Rounds each player matches before missing
+	leaves   map[int]int // Player index -> round they leave before
+	drops    map[int]int // Player index -> round they drop before
+	rounds   int
+	scores   []int
+	outcomes []string
+	forfeits []bool // Defaults to nobody forfeiting
+}
+
+func runMatchTests(t *testing.T, tests []matchTest) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMatch(t, tt.size, 1)
+			res := m.play(tt.hits, tt.leaves, tt.drops)
+
+			if res.Rounds != tt.rounds {
+				t.Errorf("rounds = %d, want %d", res.Rounds, tt.rounds)
+			}
+			if len(res.Players) != tt.size {
+				t.Fatalf("%d player results, want %d", len(res.Players), tt.size)
+			}
+			byUser := make(map[string]PlayerResult, len(res.Players))
+			for _, pr := range res.Players {
+				byUser[pr.UserID] = pr
+			}
+			var wantWinner string
+			for i, p := range m.players {
+				pr := byUser[p.UserID]
+				if pr.Score != tt.scores[i] {
+					t.Errorf("player %d score = %d, want %d", i, pr.Score, tt.scores[i])
+				}
+				if pr.Outcome != tt.outcomes[i] {
+					t.Errorf("player %d outcome = %q, want %q", i, pr.Outcome, tt.outcomes[i])
+				}
+				if want := tt.forfeits != nil && tt.forfeits[i]; pr.Forfeit != want {
+					t.Errorf("player %d forfeit = %v, want %v", i, pr.Forfeit, want)
+				}
+				if tt.outcomes[i] == OutcomeWin {
+					wantWinner = p.UserID
+				}
+			}
+			switch {
+			case res.Winner == nil && wantWinner != "":
+				t.Errorf("no winner, want %s", wantWinner)
+			case res.Winner != nil && res.Winner.UserID != wantWinner:
+				t.Errorf("winner = %s, want %q", res.Winner.UserID, wantWinner)
+			}
+			for i := 1; i < len(res.Players); i++ {
+				a, b := res.Players[i-1], res.Players[i]
+				if a.Forfeit == b.Forfeit && a.Score < b.Score {
+					t.Errorf("standings not best first: %s (%d) before %s (%d)", a.UserID, a.Score, b.UserID, b.Score)
+				}
+			}
+		})
+	}
+}
+
+func TestSunnySaysScoring(t *testing.T) {
+	runMatchTests(t, []matchTest{
+		{
+			name:     "solo scores every matched round",
+			size:     SoloPlayers,
+			hits:     []int{4},
+			rounds:   5,
+			scores:   []int{4},
+			outcomes: []string{OutcomeWin},
+		},
+		{
+			name:     "solo miss in the first round scores nothing",
+			size:     SoloPlayers,
+			hits:     []int{0},
+			rounds:   1,
+			scores:   []int{0},
+			outcomes: []string{OutcomeWin},
+		},
+		{
+			name:     "duel higher score wins",
+			size:     DuelPlayers,
+			hits:     []int{1, 3},
+			rounds:   4,
+			scores:   []int{1, 3},
+			outcomes: []string{OutcomeLoss, OutcomeWin},
+		},
+		{
+			name:     "duel equal scores draw",
+			size:     DuelPlayers,
+			hits:     []int{2, 2},
+			rounds:   3,
+			scores:   []int{2, 2},
+			outcomes: []string{OutcomeDraw, OutcomeDraw},
+		},
+		{
+			name:     "duel both out in the first round draw",
+			size:     DuelPlayers,
+			hits:     []int{0, 0},
+			rounds:   1,
+			scores:   []int{0, 0},
+			outcomes: []string{OutcomeDraw, OutcomeDraw},
+		},
+		{
+			name:     "battle players tied on top draw",
+			size:     4,
+			hits:     []int{1, 4, 4, 0},
+			rounds:   5,
+			scores:   []int{1, 4, 4, 0},
+			outcomes: []string{OutcomeLoss, OutcomeDraw, OutcomeDraw, OutcomeLoss},
+		},
+	})
+}
+
+func TestSunnySaysGameOver(t *testing.T) {
+	runMatchTests(t, []matchTest{
+		{
+			name:     "duel survivor plays on until they miss",
+			size:     DuelPlayers,
+			hits:     []int{5, 0},
+			rounds:   6,
+			scores:   []int{5, 0},
+			outcomes: []string{OutcomeWin, OutcomeLoss},
+		},
+		{
+			name:     "battle ends when one player is left standing",
+			size:     3,
+			hits:     []int{2, 0, 1},
+			rounds:   2,
+			scores:   []int{2, 0, 1},
+			outcomes: []string{OutcomeWin, OutcomeLoss, OutcomeLoss},
+		},
+		{
+			name:     "battle last two out in the same round draw",
+			size:     3,
+			hits:     []int{2, 0, 2},
+			rounds:   3,
+			scores:   []int{2, 0, 2},
+			outcomes: []string{OutcomeDraw, OutcomeLoss, OutcomeDraw},
+		},
+		{
+			name:     "duel leaver forfeits despite the higher score",
+			size:     DuelPlayers,
+			hits:     []int{9, 1},
+			leaves:   map[int]int{0: 4},
+			rounds:   3,
+			scores:   []int{3, 1},
+			outcomes: []string{OutcomeLoss, OutcomeWin},
+			forfeits: []bool{true, false},
+		},
+		{
+			name:     "battle leaver leaving one standing ends the match",
+			size:     3,
+			hits:     []int{9, 9, 0},
+			leaves:   map[int]int{1: 3},
+			rounds:   2,
+			scores:   []int{2, 2, 0},
+			outcomes: []string{OutcomeWin, OutcomeLoss, OutcomeLoss},
+			forfeits: []bool{false, true, false},
+		},
+		{
+			name:     "dropped player forfeits after the grace period",
+			size:     DuelPlayers,
+			hits:     []int{2, 9},
+			drops:    map[int]int{1: 2},
+			rounds:   3,
+			scores:   []int{2, 1},
+			outcomes: []string{OutcomeWin, OutcomeLoss},
+			forfeits: []bool{false, true},
+		},
+	})
+}
+
+func TestSunnySaysConfusion(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+		hits []int
+		seed int64
+	}{
+		{name: "solo", size: SoloPlayers, hits: []int{12}, seed: 1},
+		{name: "duel led by one player", size: DuelPlayers, hits: []int{1, 12}, seed: 2},
+		{name: "battle", size: 3, hits: []int{12, 2, 12}, seed: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMatch(t, tt.size, tt.seed)
+			m.play(tt.hits, nil, nil)
+
+			// Nobody can have confusionScore points before round confusionScore+1
+			flashed := false
+			for i, r := range m.rounds {
+				round := i + 1
+				if want := round > confusionScore; r.confusion != want {
+					t.Errorf("round %d confusion = %v, want %v", round, r.confusion, want)
+				}
+				if r.flashes < 0 || r.flashes > maxFlashes || (!r.confusion && r.flashes > 0) {
+					t.Errorf("round %d has %d flashes", round, r.flashes)
+				}
+				flashed = flashed || r.flashes > 0
+			}
+			if !flashed {
+				t.Error("no round had confusion flashes")
+			}
+
+			// The same seed plays the same match
+			again := newTestMatch(t, tt.size, tt.seed)
+			again.play(tt.hits, nil, nil)
+			if fmt.Sprint(again.rounds) != fmt.Sprint(m.rounds) {
+				t.Errorf("replay with the same seed differs:\n%v\n%v", again.rounds, m.rounds)
+			}
+		})
+	}
+}
+
+func TestMatchmakeNeverPairsAUserWithThemselves(t *testing.T) {
+	rm := NewRoomManager(func(MatchResult) MatchRecord { return MatchRecord{} })
+	first := rm.Matchmake(NewPlayer("user-a", testConn(t)), DuelPlayers)
+	second := rm.Matchmake(NewPlayer("user-a", testConn(t)), DuelPlayers)
+	t.Cleanup(first.cancel)
+	t.Cleanup(second.cancel)
+	if first == second {
+		t.Fatal("second connection was matched into the user's own room")
+	}
+
+	// The queue must not merge the two rooms either
+	rm.matchWaiting()
+	for _, room := range []*SunnySaysRoom{first, second} {
+		if count, _, ok := room.queued(); !ok || count != 1 {
+			t.Errorf("room has %d queued players (ok=%v), want 1", count, ok)
+		}
+	}
+
+	if other := rm.Matchmake(NewPlayer("user-b", testConn(t)), DuelPlayers); other != first && other != second {
+		t.Error("another user was not matched into a waiting room")
+	}
+
+	private := rm.CreatePrivateRoom(NewPlayer("user-c", testConn(t)), DuelPlayers)
+	t.Cleanup(private.cancel)
+	if private.Join(NewPlayer("user-c", testConn(t))) {
+		t.Error("user joined their own private room by code")
+	}
+}
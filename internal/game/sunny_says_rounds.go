@@ -0,0 +1,471 @@
+package game
+
+import (
+	"sort"
+	"time"
+)
+
+// Round timing
+const (
+	minRoundWait  = 500 * time.Millisecond  // Shortest wait before Sunny shows a symbol
+	maxRoundWait  = 3000 * time.Millisecond // Longest wait before Sunny shows a symbol
+	flashDuration = 300 * time.Millisecond  // How long a confusion flash is shown
+	minFlashPause = 300 * time.Millisecond  // Shortest idle time between flashes
+	maxFlashPause = 1000 * time.Millisecond // Longest idle time between flashes
+	flashSettle   = 100 * time.Millisecond  // Brief pause added to the idle time between flashes
+	matchTimeout  = time.Second             // Time players have to match Sunny's final symbol
+	roundCooldown = 500 * time.Millisecond  // Pause after the results before the next round can start
+
+	// confusionScore is the score at which Sunny starts flashing decoy symbols
+	confusionScore = 3
+	// maxFlashes is the most decoy symbols Sunny flashes in a round
+	maxFlashes = 3
+)
+
+// Match outcomes
+const (
+	OutcomeWin  = "win"
+	OutcomeLoss = "loss"
+	OutcomeDraw = "draw"
+)
+
+// MatchResult is a finished match, handed to the room manager's record func
+type MatchResult struct {
+	RoomID    string
+	Private   bool
+	Solo      bool
+	Battle    bool
+	SessionID string // The game session that paid for a solo game
+	Rounds    int
+	StartedAt time.Time
+	EndedAt   time.Time
+	Winner    *PlayerResult  // nil for a draw
+	Players   []PlayerResult // Everyone who took part, best first
+}
+
+// PlayerResult is one player's part in a finished match
+type PlayerResult struct {
+	PlayerID       string
+	UserID         string
+	Score          int
+	EndedAt        time.Time
+	Forfeit        bool   // Left the match before their game ended
+	Outcome        string // OutcomeWin, OutcomeLoss or OutcomeDraw
+	OpponentUserID string // The other player of a 1v1 match
+}
+
+// MatchRecord is what storing a match produced: its ID and each player's
+// rewards by player ID, sent to the players with game over
+type MatchRecord struct {
+	MatchID string
+	Rewards map[string]any
+}
+
+// inMatch runs f after d, unless the match that was running when it was
+// set has ended by then
+func (r *SunnySaysRoom) inMatch(d time.Duration, f func()) {
+	match := r.match
+	r.after(d, func() {
+		if r.match == match && r.state == RoomStatePlaying {
+			f()
+		}
+	})
+}
+
+func (r *SunnySaysRoom) setReady(player *Player) {
+	if r.state != RoomStatePlaying || !r.has(player) || player.gameOver {
+		return
+	}
+	player.ready = true
+	r.maybeStartRound()
+}
+
+// maybeStartRound starts the next round once every player still in the game is ready
+func (r *SunnySaysRoom) maybeStartRound() {
+	if r.state != RoomStatePlaying || r.roundActive {
+		return
+	}
+	active := false
+	for _, p := range r.players {
+		if p.gameOver {
+			continue
+		}
+		if !p.ready {
+			return
+		}
+		active = true
+	}
+	if active {
+		r.resetReady()
+		r.startRound()
+	}
+}
+
+func (r *SunnySaysRoom) resetReady() {
+	for _, p := range r.players {
+		p.ready = false
+	}
+}
+
+// startRound tells the players a round has begun and has Sunny show a
+// symbol after a random wait. Once any player reaches confusionScore, half
+// the rounds open with a few decoy flashes.
+func (r *SunnySaysRoom) startRound() {
+	r.round++
+	r.roundActive = true
+	if !r.confusionEnabled {
+		for _, p := range r.players {
+			if p.score >= confusionScore {
+				r.confusionEnabled = true
+				break
+			}
+		}
+	}
+	r.flashesLeft = 0
+	if r.confusionEnabled && r.rand.Float32() < 0.5 {
+		r.flashesLeft = r.rand.Intn(maxFlashes) + 1
+	}
+	r.symbol = r.randomSymbol()
+
+	start := Message{Type: MsgTypeRoundStart, Round: r.round}
+	for _, p := range r.players {
+		if !p.gameOver {
+			p.frame = 0
+			p.Send(start)
+		}
+	}
+	r.spectate(start)
+
+	r.inMatch(r.randomDuration(minRoundWait, maxRoundWait), r.nextSunnyFrame)
+}
+
+// nextSunnyFrame shows the next decoy flash followed by an idle pause, or
+// Sunny's final symbol once the flashes are done
+func (r *SunnySaysRoom) nextSunnyFrame() {
+	if r.flashesLeft > 0 {
+		r.flashesLeft--
+		r.showSunny(r.randomSymbol(), flashDuration)
+		r.inMatch(flashDuration, func() {
+			if r.flashesLeft == 0 {
+				r.nextSunnyFrame()
+				return
+			}
+			idle := r.randomDuration(minFlashPause, maxFlashPause) + flashSettle
+			r.showSunny(0, idle)
+			r.inMatch(idle, r.nextSunnyFrame)
+		})
+		return
+	}
+
+	// The final symbol stays up until the round ends
+	r.showSunny(r.symbol, 0)
+	r.inMatch(matchTimeout, r.roundResults)
+}
+
+// showSunny sends Sunny's frame to the players still in the game and to
+// spectators, to be shown for d (0 for the final symbol of a round)
+func (r *SunnySaysRoom) showSunny(frame int, d time.Duration) {
+	r.sunnyFrame = frame
+	msg := Message{
+		Type:              MsgTypeSunnyFrame,
+		SunnyFrame:        frame,
+		DisplayDurationMs: int(d.Milliseconds()),
+	}
+	for _, p := range r.players {
+		if !p.gameOver {
+			p.Send(msg)
+		}
+	}
+	r.spectate(msg)
+}
+
+// roundResults scores the round: players showing Sunny's symbol score a
+// point and everyone else is out
+func (r *SunnySaysRoom) roundResults() {
+	now := r.clock.Now()
+	for _, p := range r.players {
+		if p.gameOver {
+			continue
+		}
+		if p.frame == r.symbol {
+			p.score++
+			p.Send(Message{Type: MsgTypeRoundResult, Score: p.score, Frame: p.frame})
+			continue
+		}
+
+		p.setGameOver(now)
+		p.Send(Message{Type: MsgTypeGameOver, Score: p.score})
+		out := Message{Type: MsgTypePlayerOut, PlayerID: p.ID, Score: p.score}
+		r.spectate(out)
+		if r.IsBattle() {
+			for _, o := range otherPlayers(r.players, p) {
+				o.Send(out)
+			}
+		} else if opponent := r.opponent(p); opponent != nil {
+			opponent.Send(Message{Type: MsgTypeOpponentGameOver})
+		}
+	}
+
+	board := Message{Type: MsgTypeScoreboard, Round: r.round, Scoreboard: r.scoreboard()}
+	r.spectate(board)
+	if r.IsBattle() {
+		r.broadcast(board)
+		// Once everyone else is out the survivor has won; ending their game ends the match
+		if winner := r.lastStanding(); winner != nil {
+			winner.setGameOver(now)
+		}
+	}
+	r.checkGameOver()
+
+	// Ready messages sent before the cooldown ends are for this round
+	r.inMatch(roundCooldown, func() {
+		r.roundActive = false
+		r.resetReady()
+	})
+}
+
+// checkGameOver ends the match once every player still in the room is out
+func (r *SunnySaysRoom) checkGameOver() {
+	if r.state != RoomStatePlaying {
+		return
+	}
+	for _, p := range r.players {
+		if !p.gameOver {
+			return
+		}
+	}
+	r.endMatch()
+}
+
+// endMatch stores the result off the room's goroutine, then finishes the
+// match with what was stored
+func (r *SunnySaysRoom) endMatch() {
+	r.state = RoomStateSaving
+	r.roundActive = false
+	res := r.result(r.clock.Now())
+	go func() {
+		var rec MatchRecord
+		if r.record != nil {
+			rec = r.record(res)
+		}
+		r.post(func() { r.finishMatch(res, rec) })
+	}()
+}
+
+// finishMatch sends game over with the final standings to the players and
+// spectators. Everyone still here can then vote on a rematch in the same room.
+func (r *SunnySaysRoom) finishMatch(res MatchResult, rec MatchRecord) {
+	final := Message{Type: MsgTypeGameOver, MatchID: rec.MatchID}
+	if !r.IsSolo() && res.Winner != nil {
+		final.WinnerID = res.Winner.PlayerID
+	}
+	if r.IsBattle() {
+		final.Scoreboard = r.scoreboard()
+	}
+	for _, p := range r.players {
+		msg := final
+		msg.Result = rec.Rewards[p.ID]
+		p.Send(msg)
+	}
+
+	// Spectators get the final standings, then the show is over
+	final.Scoreboard = r.scoreboard()
+	for _, s := range r.spectators {
+		s.Send(final)
+		s.Conn.Close()
+	}
+	r.spectators = nil
+
+	if !r.IsSolo() && len(r.players) >= MinRoomPlayers {
+		r.openRematch()
+		return
+	}
+	r.end()
+}
+
+// openRematch asks the players still in the room whether they want a
+// rematch. It starts if every player accepts within RematchTimeout.
+func (r *SunnySaysRoom) openRematch() {
+	r.state = RoomStateRematch
+	r.rematchVotes = make(map[string]bool, len(r.players))
+	r.broadcast(Message{
+		Type:     MsgTypeRematchOffer,
+		WaitTime: int(RematchTimeout.Milliseconds()),
+	})
+
+	match := r.match
+	r.after(RematchTimeout, func() {
+		if r.match == match && r.state == RoomStateRematch {
+			r.declineRematch()
+		}
+	})
+}
+
+func (r *SunnySaysRoom) voteRematch(player *Player, accept bool) {
+	if r.state != RoomStateRematch || !r.has(player) {
+		return
+	}
+	if !accept {
+		r.declineRematch()
+		return
+	}
+	if r.rematchVotes[player.ID] {
+		return
+	}
+	r.rematchVotes[player.ID] = true
+	for _, p := range otherPlayers(r.players, player) {
+		p.Send(Message{Type: MsgTypeRematchVoted, PlayerID: player.ID})
+	}
+	for _, p := range r.players {
+		if !r.rematchVotes[p.ID] {
+			return
+		}
+	}
+
+	// Everyone accepted; play again with the same players
+	r.rematchVotes = nil
+	for _, p := range r.players {
+		p.reset()
+	}
+	r.round = 0
+	r.sunnyFrame = 0
+	r.flashesLeft = 0
+	r.confusionEnabled = false
+	r.startMatch()
+}
+
+// declineRematch sends the players back to the lobby and ends the room
+func (r *SunnySaysRoom) declineRematch() {
+	r.rematchVotes = nil
+	for _, p := range r.players {
+		p.Send(Message{Type: MsgTypeRematchDeclined})
+		p.Connection().Close()
+	}
+	r.end()
+}
+
+// lastStanding returns the winner of a battle once every other player is out:
+// the only player still in the game, if they had company when the match started.
+// It returns nil for 1v1 rooms, where the remaining player plays on.
+func (r *SunnySaysRoom) lastStanding() *Player {
+	if !r.IsBattle() || len(r.participants) < MinRoomPlayers {
+		return nil
+	}
+	var standing *Player
+	for _, p := range r.players {
+		if p.gameOver {
+			continue
+		}
+		if standing != nil {
+			return nil
+		}
+		standing = p
+	}
+	return standing
+}
+
+// standings returns the participants best first: players who stayed rank
+// above those who forfeited, then by score, then by who lasted longest
+func (r *SunnySaysRoom) standings() []*Player {
+	out := append([]*Player(nil), r.participants...)
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.forfeit != b.forfeit {
+			return !a.forfeit
+		}
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		return a.endedAt.After(b.endedAt)
+	})
+	return out
+}
+
+// winner returns the player who won the match: the best player who didn't
+// forfeit, if nobody else who stayed has the same score. It is nil for a draw.
+func winner(standings []*Player) *Player {
+	if len(standings) == 0 || standings[0].forfeit {
+		return nil
+	}
+	if len(standings) > 1 && !standings[1].forfeit && standings[1].score == standings[0].score {
+		return nil
+	}
+	return standings[0]
+}
+
+// outcome is a win for the match's winner and a loss for everyone else,
+// or a draw for the players who stayed and share the top score
+func outcome(p, winner *Player, standings []*Player) string {
+	switch {
+	case winner == p:
+		return OutcomeWin
+	case winner == nil && !p.forfeit && !standings[0].forfeit && p.score == standings[0].score:
+		return OutcomeDraw
+	}
+	return OutcomeLoss
+}
+
+// result describes the match that ended at endedAt
+func (r *SunnySaysRoom) result(endedAt time.Time) MatchResult {
+	standings := r.standings()
+	best := winner(standings)
+	res := MatchResult{
+		RoomID:    r.ID,
+		Private:   r.Private,
+		Solo:      r.IsSolo(),
+		Battle:    r.IsBattle(),
+		SessionID: r.SessionID,
+		Rounds:    r.round,
+		StartedAt: r.startedAt,
+		EndedAt:   endedAt,
+		Players:   make([]PlayerResult, 0, len(standings)),
+	}
+	for _, p := range standings {
+		pr := PlayerResult{
+			PlayerID: p.ID,
+			UserID:   p.UserID,
+			Score:    p.score,
+			EndedAt:  p.endedAt,
+			Forfeit:  p.forfeit,
+			Outcome:  outcome(p, best, standings),
+		}
+		if pr.EndedAt.IsZero() {
+			pr.EndedAt = endedAt
+		}
+		if res.Battle || res.Solo {
+			res.Players = append(res.Players, pr)
+			continue
+		}
+		for _, o := range r.participants {
+			if o != p {
+				pr.OpponentUserID = o.UserID
+			}
+		}
+		res.Players = append(res.Players, pr)
+	}
+	if best != nil {
+		res.Winner = &res.Players[0]
+	}
+	return res
+}
+
+// scoreboard returns the room's standings for a scoreboard message
+func (r *SunnySaysRoom) scoreboard() []ScoreEntry {
+	standings := r.standings()
+	out := make([]ScoreEntry, 0, len(standings))
+	for _, p := range standings {
+		out = append(out, ScoreEntry{PlayerID: p.ID, Score: p.score, Out: p.gameOver, Forfeit: p.forfeit})
+	}
+	return out
+}
+
+// randomSymbol returns one of Sunny's symbols: 1 (heart), 2 (diamond) or 3 (both).
+// Frame 0 (nothing) is never Sunny's choice.
+func (r *SunnySaysRoom) randomSymbol() int {
+	return r.rand.Intn(3) + 1
+}
+
+// randomDuration returns a duration in [min, max)
+func (r *SunnySaysRoom) randomDuration(min, max time.Duration) time.Duration {
+	return min + time.Duration(r.rand.Int63n(int64(max-min)))
+}
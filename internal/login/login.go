@@ -0,0 +1,61 @@
+// Package login defines the daily login reward cycle. A login day is a calendar
+// day in the user's time zone; consecutive days build a streak whose reward
+// escalates over a 7-day cycle and starts over after a missed day.
+package login
+
+import (
+	"time"
+	// Embed the time zone database so user time zones resolve on minimal images
+	_ "time/tzdata"
+)
+
+// CycleLength is the number of days before the rewards repeat
+const CycleLength = 7
+
+// Reward is granted when a login day is claimed
+type Reward struct {
+	Coins  int `json:"coins"`
+	Energy int `json:"energy"`
+}
+
+// Rewards lists the reward for each day of the cycle
+var Rewards = [CycleLength]Reward{
+	{Coins: 10, Energy: 10},
+	{Coins: 15, Energy: 10},
+	{Coins: 20, Energy: 10},
+	{Coins: 25, Energy: 15},
+	{Coins: 30, Energy: 15},
+	{Coins: 40, Energy: 15},
+	{Coins: 75, Energy: 25},
+}
+
+// CycleDay returns the 1-based position in the cycle of the streak'th consecutive day
+func CycleDay(streak int) int {
+	if streak < 1 {
+		return 1
+	}
+	return (streak-1)%CycleLength + 1
+}
+
+// RewardFor returns the reward for the streak'th consecutive day
+func RewardFor(streak int) Reward {
+	return Rewards[CycleDay(streak)-1]
+}
+
+// Location resolves a stored time zone name, falling back to UTC
+func Location(name string) *time.Location {
+	if loc, err := time.LoadLocation(name); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// Day returns the login day containing now in loc, as midnight UTC of that date
+// (the form DATE columns are read and written in), and when the next day starts
+func Day(now time.Time, loc *time.Location) (day, next time.Time) {
+	local := now.In(loc)
+	y, m, d := local.Date()
+	day = time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	next = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+	return day, next
+}
@@ -0,0 +1,79 @@
+package login
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCycleDay(t *testing.T) {
+	tests := []struct {
+		streak int
+		want   int
+	}{
+		{-1, 1},
+		{0, 1},
+		{1, 1},
+		{2, 2},
+		{CycleLength, CycleLength},
+		{CycleLength + 1, 1},
+		{2*CycleLength + 3, 3},
+	}
+	for _, tt := range tests {
+		if got := CycleDay(tt.streak); got != tt.want {
+			t.Errorf("CycleDay(%d) = %d, want %d", tt.streak, got, tt.want)
+		}
+	}
+}
+
+func TestRewardFor(t *testing.T) {
+	if got := RewardFor(CycleLength); got != Rewards[CycleLength-1] {
+		t.Errorf("RewardFor(%d) = %+v, want the last reward of the cycle", CycleLength, got)
+	}
+	if got := RewardFor(CycleLength + 1); got != Rewards[0] {
+		t.Errorf("RewardFor(%d) = %+v, want the cycle to start over", CycleLength+1, got)
+	}
+}
+
+func TestDay(t *testing.T) {
+	tokyo := Location("Asia/Tokyo")
+	newYork := Location("America/New_York")
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		loc      *time.Location
+		wantDay  time.Time
+		wantNext time.Time
+	}{
+		{
+			"utc", time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC), time.UTC,
+			time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			"ahead of utc is already tomorrow", time.Date(2026, 10, 18, 20, 0, 0, 0, time.UTC), tokyo,
+			time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 20, 0, 0, 0, 0, tokyo),
+		},
+		{
+			"behind utc is still yesterday", time.Date(2026, 10, 18, 2, 0, 0, 0, time.UTC), newYork,
+			time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 18, 0, 0, 0, 0, newYork),
+		},
+		{
+			"day ending on a DST change", time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC), newYork,
+			time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 11, 2, 0, 0, 0, 0, newYork),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			day, next := Day(tt.now, tt.loc)
+			if !day.Equal(tt.wantDay) || !next.Equal(tt.wantNext) {
+				t.Errorf("Day() = %v, %v, want %v, %v", day, next, tt.wantDay, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestLocationFallsBackToUTC(t *testing.T) {
+	if got := Location("Not/AZone"); got != time.UTC {
+		t.Errorf("Location() = %v, want UTC", got)
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminChecker reports whether a user has admin rights
+type AdminChecker interface {
+	IsAdmin(ctx context.Context, userID string) (bool, error)
+}
+
+// RequireAdmin only lets admins through. It must run after JWTMiddleware.Require.
+func RequireAdmin(checker AdminChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := UserID(c)
+		if userID == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		admin, err := checker.IsAdmin(c, userID)
+		if err != nil || !admin {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoginRecorder records that a user was active today and returns when their next day starts
+type LoginRecorder interface {
+	RecordLogin(ctx context.Context, userID string) (time.Time, error)
+}
+
+// RecordLogins records the first authenticated request of each user's day.
+// It must run after JWTMiddleware.Require. Users already recorded for their
+// current day are skipped without a database round trip, and failures are
+// logged rather than failing the request.
+func RecordLogins(rec LoginRecorder) gin.HandlerFunc {
+	var mu sync.Mutex
+	recordedUntil := map[string]time.Time{}
+
+	return func(c *gin.Context) {
+		userID := UserID(c)
+		if userID == "" {
+			c.Next()
+			return
+		}
+
+		now := time.Now()
+		mu.Lock()
+		until, ok := recordedUntil[userID]
+		mu.Unlock()
+		if ok && now.Before(until) {
+			c.Next()
+			return
+		}
+
+		next, err := rec.RecordLogin(c, userID)
+		if err != nil {
+			log.Printf("ERROR: Failed to record login for user %s: %v", userID, err)
+			c.Next()
+			return
+		}
+		mu.Lock()
+		// Drop users whose day has ended so the map only holds active users
+		for id, t := range recordedUntil {
+			if !now.Before(t) {
+				delete(recordedUntil, id)
+			}
+		}
+		recordedUntil[userID] = next
+		mu.Unlock()
+		c.Next()
+	}
+}
@@ -0,0 +1,105 @@
+package pet
+
+import (
+	"time"
+)
+
+// Attribute keys used to persist the state inside pets.attrs.
+// Happiness is stored under "mood" so existing pets keep their value.
+const (
+	attrXP          = "xp"
+	attrStage       = "stage"
+	attrAsleepUntil = "asleep_until"
+	attrCooldowns   = "cooldowns"
+)
+
+func attrKey(stat Stat) string {
+	if stat == StatHappiness {
+		return "mood"
+	}
+	return string(stat)
+}
+
+func attrUpdatedKey(stat Stat) string {
+	return attrKey(stat) + "_last_updated"
+}
+
+// FromAttrs decodes the state stored in pets.attrs.
+// Missing stats fall back to the species' base stats and missing timestamps
+// to fallback (usually the pet's updated_at).
+func FromAttrs(attrs map[string]any, sp *Species, fallback time.Time) State {
+	s := State{LastUpdated: make(map[Stat]time.Time, len(AllStats))}
+	for _, stat := range AllStats {
+		v, ok := intAttr(attrs, attrKey(stat))
+		if !ok {
+			v = sp.BaseStats[stat]
+		}
+		*s.field(stat) = clamp(v)
+
+		s.LastUpdated[stat] = fallback
+		if t, ok := timeAttr(attrs[attrUpdatedKey(stat)]); ok {
+			s.LastUpdated[stat] = t
+		}
+	}
+	xp, _ := intAttr(attrs, attrXP)
+	s.AddXP(xp)
+	s.Stage, _ = attrs[attrStage].(string)
+	if t, ok := timeAttr(attrs[attrAsleepUntil]); ok {
+		s.AsleepUntil = &t
+	}
+	if cds, ok := attrs[attrCooldowns].(map[string]any); ok {
+		s.Cooldowns = make(map[string]time.Time, len(cds))
+		for action, v := range cds {
+			if t, ok := timeAttr(v); ok {
+				s.Cooldowns[action] = t
+			}
+		}
+	}
+	return s
+}
+
+// WriteAttrs stores the state into attrs, preserving unrelated keys
+func (s *State) WriteAttrs(attrs map[string]any) {
+	for _, stat := range AllStats {
+		attrs[attrKey(stat)] = s.Get(stat)
+		attrs[attrUpdatedKey(stat)] = s.LastUpdated[stat].UTC().Format(time.RFC3339)
+	}
+	attrs[attrXP] = s.XP
+	if s.Stage != "" {
+		attrs[attrStage] = s.Stage
+	}
+
+	now := time.Now()
+	if s.AsleepUntil != nil && s.AsleepUntil.After(now) {
+		attrs[attrAsleepUntil] = s.AsleepUntil.UTC().Format(time.RFC3339)
+	} else {
+		delete(attrs, attrAsleepUntil)
+	}
+	cds := make(map[string]any, len(s.Cooldowns))
+	for action, t := range s.Cooldowns {
+		// Expired cooldowns no longer matter
+		if t.After(now) {
+			cds[action] = t.UTC().Format(time.RFC3339)
+		}
+	}
+	attrs[attrCooldowns] = cds
+}
+
+func timeAttr(v any) (time.Time, bool) {
+	str, ok := v.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, str)
+	return t, err == nil
+}
+
+func intAttr(attrs map[string]any, key string) (int, bool) {
+	switch v := attrs[key].(type) {
+	case float64:
+		return int(v), true
+	case int:
+		return v, true
+	}
+	return 0, false
+}
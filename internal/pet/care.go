@@ -0,0 +1,95 @@
+package pet
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Care action ids
+const (
+	CareFeed  = "feed"
+	CarePet   = "pet"
+	CareClean = "clean"
+	CareSleep = "sleep"
+)
+
+// CareAction is an interaction the owner can perform on their pet without a minigame
+type CareAction struct {
+	ID         string       `json:"id"`
+	EnergyCost int          `json:"energy_cost"`
+	Cooldown   Seconds      `json:"cooldown_seconds"`
+	Effects    map[Stat]int `json:"effects"`
+	XP         int          `json:"xp"`
+	// SleepFor puts the pet to sleep; a sleeping pet accepts no care actions until it wakes
+	SleepFor Seconds `json:"sleep_seconds,omitempty"`
+}
+
+// CareActions are the available care actions keyed by id
+var CareActions = map[string]CareAction{
+	CareFeed: {
+		ID: CareFeed, EnergyCost: 2, Cooldown: Seconds(10 * time.Minute), XP: 5,
+		Effects: map[Stat]int{StatHunger: 25, StatHappiness: 2},
+	},
+	CarePet: {
+		ID: CarePet, EnergyCost: 1, Cooldown: Seconds(2 * time.Minute), XP: 2,
+		Effects: map[Stat]int{StatHappiness: 8},
+	},
+	CareClean: {
+		ID: CareClean, EnergyCost: 3, Cooldown: Seconds(30 * time.Minute), XP: 5,
+		Effects: map[Stat]int{StatHygiene: 40, StatHappiness: 3},
+	},
+	CareSleep: {
+		ID: CareSleep, EnergyCost: 0, Cooldown: Seconds(4 * time.Hour), XP: 10,
+		Effects:  map[Stat]int{StatHappiness: 15},
+		SleepFor: Seconds(20 * time.Minute),
+	},
+}
+
+// ErrAsleep is returned when acting on a sleeping pet
+var ErrAsleep = errors.New("pet is asleep")
+
+// CooldownError is returned when a care action is used again too soon
+type CooldownError struct {
+	Action    string
+	Remaining time.Duration
+}
+
+func (e *CooldownError) Error() string {
+	return fmt.Sprintf("%s is on cooldown for %s", e.Action, e.Remaining.Round(time.Second))
+}
+
+// Seconds is a duration encoded in JSON as whole seconds
+type Seconds time.Duration
+
+func (s Seconds) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprint(int64(time.Duration(s) / time.Second))), nil
+}
+
+// ApplyCare checks the action's cooldown and the pet's sleep, then applies its
+// stat effects and XP. Energy is not handled here.
+func (s *State) ApplyCare(a CareAction, now time.Time) error {
+	if s.AsleepUntil != nil && now.Before(*s.AsleepUntil) {
+		return ErrAsleep
+	}
+	if until, ok := s.Cooldowns[a.ID]; ok && now.Before(until) {
+		return &CooldownError{Action: a.ID, Remaining: until.Sub(now)}
+	}
+
+	for stat, delta := range a.Effects {
+		s.Add(stat, delta, now)
+	}
+	s.AddXP(a.XP)
+
+	if s.Cooldowns == nil {
+		s.Cooldowns = make(map[string]time.Time)
+	}
+	s.Cooldowns[a.ID] = now.Add(time.Duration(a.Cooldown))
+	if a.SleepFor > 0 {
+		wake := now.Add(time.Duration(a.SleepFor))
+		s.AsleepUntil = &wake
+	} else {
+		s.AsleepUntil = nil
+	}
+	return nil
+}
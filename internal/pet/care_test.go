@@ -0,0 +1,91 @@
+package pet
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestApplyCare(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time { t := now.Add(d); return &t }
+	feed := CareActions[CareFeed]
+
+	tests := []struct {
+		name          string
+		action        CareAction
+		asleepUntil   *time.Time
+		cooldowns     map[string]time.Time
+		wantErr       error
+		wantRemaining time.Duration // For a CooldownError
+	}{
+		{"fresh pet", feed, nil, nil, nil, 0},
+		{"asleep", feed, at(time.Minute), nil, ErrAsleep, 0},
+		{"just woke up", feed, at(0), nil, nil, 0},
+		{"on cooldown", feed, nil, map[string]time.Time{CareFeed: now.Add(90 * time.Second)}, &CooldownError{}, 90 * time.Second},
+		{"cooldown over", feed, nil, map[string]time.Time{CareFeed: now}, nil, 0},
+		{"other action on cooldown", feed, nil, map[string]time.Time{CarePet: now.Add(time.Hour)}, nil, 0},
+		{"sleep is refused while asleep", CareActions[CareSleep], at(time.Minute), nil, ErrAsleep, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := State{Hunger: 50, Happiness: 50, LastUpdated: map[Stat]time.Time{}, AsleepUntil: tt.asleepUntil, Cooldowns: tt.cooldowns}
+			err := s.ApplyCare(tt.action, now)
+
+			var cd *CooldownError
+			switch {
+			case tt.wantErr == nil:
+				if err != nil {
+					t.Fatalf("ApplyCare() error = %v", err)
+				}
+			case errors.As(tt.wantErr, &cd):
+				if !errors.As(err, &cd) || cd.Action != tt.action.ID || cd.Remaining != tt.wantRemaining {
+					t.Fatalf("ApplyCare() error = %v, want %s on cooldown for %s", err, tt.action.ID, tt.wantRemaining)
+				}
+			default:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ApplyCare() error = %v, want %v", err, tt.wantErr)
+				}
+			}
+			if err != nil {
+				if s.Hunger != 50 || s.XP != 0 {
+					t.Errorf("refused action changed the pet: hunger %d, xp %d", s.Hunger, s.XP)
+				}
+				return
+			}
+			if s.Hunger != 75 || s.Happiness != 52 || s.XP != feed.XP {
+				t.Errorf("hunger %d, happiness %d, xp %d, want 75, 52, %d", s.Hunger, s.Happiness, s.XP, feed.XP)
+			}
+			if got := s.Cooldowns[CareFeed]; !got.Equal(now.Add(time.Duration(feed.Cooldown))) {
+				t.Errorf("feed cooldown ends %v, want %v", got, now.Add(time.Duration(feed.Cooldown)))
+			}
+		})
+	}
+}
+
+func TestApplyCareSleep(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := State{Happiness: 95, LastUpdated: map[Stat]time.Time{}}
+	sleep := CareActions[CareSleep]
+
+	if err := s.ApplyCare(sleep, now); err != nil {
+		t.Fatal(err)
+	}
+	if s.AsleepUntil == nil || !s.AsleepUntil.Equal(now.Add(time.Duration(sleep.SleepFor))) {
+		t.Fatalf("asleep until %v, want %v", s.AsleepUntil, now.Add(time.Duration(sleep.SleepFor)))
+	}
+	if s.Happiness != StatMax {
+		t.Errorf("happiness = %d, want it clamped to %d", s.Happiness, StatMax)
+	}
+
+	// Every action waits until the pet wakes; afterwards it is awake again
+	if err := s.ApplyCare(CareActions[CarePet], now.Add(time.Minute)); !errors.Is(err, ErrAsleep) {
+		t.Fatalf("pet while asleep: error = %v, want ErrAsleep", err)
+	}
+	if err := s.ApplyCare(CareActions[CarePet], *s.AsleepUntil); err != nil {
+		t.Fatalf("pet after waking: %v", err)
+	}
+	if s.AsleepUntil != nil {
+		t.Errorf("asleep until %v after another action, want awake", s.AsleepUntil)
+	}
+}
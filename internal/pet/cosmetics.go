@@ -0,0 +1,76 @@
+package pet
+
+import (
+	"errors"
+)
+
+// Slot is where a cosmetic item is worn. Each slot holds at most one item.
+type Slot string
+
+const (
+	SlotHat        Slot = "hat"
+	SlotCollar     Slot = "collar"
+	SlotBackground Slot = "background"
+)
+
+// Slots lists the cosmetic slots in render order
+var Slots = []Slot{SlotBackground, SlotCollar, SlotHat}
+
+const attrLoadout = "loadout"
+
+// ErrWrongSlot is returned when equipping an item in a slot it does not fit
+var ErrWrongSlot = errors.New("item does not fit this slot")
+
+// ValidSlot reports whether s is a known slot
+func ValidSlot(s Slot) bool {
+	for _, slot := range Slots {
+		if slot == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Loadout maps each slot to the id of the equipped cosmetic item
+type Loadout map[Slot]string
+
+// Equip puts the cosmetic item in slot, replacing whatever was there
+func (l Loadout) Equip(slot Slot, it Item) error {
+	if it.Category != ItemCosmetic || it.Slot != slot {
+		return ErrWrongSlot
+	}
+	l[slot] = it.ID
+	return nil
+}
+
+// LoadoutFromAttrs decodes the equipped items stored in pets.attrs
+func LoadoutFromAttrs(attrs map[string]any) Loadout {
+	l := Loadout{}
+	raw, _ := attrs[attrLoadout].(map[string]any)
+	for k, v := range raw {
+		if id, ok := v.(string); ok && ValidSlot(Slot(k)) {
+			l[Slot(k)] = id
+		}
+	}
+	return l
+}
+
+// WriteAttrs stores the loadout into attrs
+func (l Loadout) WriteAttrs(attrs map[string]any) {
+	raw := make(map[string]any, len(l))
+	for slot, id := range l {
+		raw[string(slot)] = id
+	}
+	attrs[attrLoadout] = raw
+}
+
+// Appearance is everything a client needs to render a pet, including for
+// other players (e.g. opponents in Sunny Says)
+type Appearance struct {
+	PetID   string  `json:"pet_id"`
+	Name    string  `json:"name"`
+	Species string  `json:"species"`
+	Stage   string  `json:"stage"`
+	Level   int     `json:"level"`
+	Loadout Loadout `json:"loadout"`
+}
@@ -0,0 +1,18 @@
+package pet
+
+import "time"
+
+// Event types emitted when a pet changes in a way the client should animate
+const (
+	EventEvolved = "evolved"
+)
+
+// Event is a notable change to a pet, e.g. reaching a new evolution stage
+type Event struct {
+	ID        string    `json:"id,omitempty"`
+	Type      string    `json:"type"`
+	From      string    `json:"from,omitempty"`
+	To        string    `json:"to,omitempty"`
+	Level     int       `json:"level,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,128 @@
+package pet
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Item categories
+const (
+	ItemFood     = "food"
+	ItemToy      = "toy"
+	ItemCare     = "care"
+	ItemCosmetic = "cosmetic"
+)
+
+//go:embed items.json
+var embeddedItems []byte
+
+// Item is an entry in the shop catalogue
+type Item struct {
+	ID       string       `json:"id"`
+	Name     string       `json:"name"`
+	Category string       `json:"category"`
+	Price    int          `json:"price"`
+	Effects  map[Stat]int `json:"effects,omitempty"`
+	XP       int          `json:"xp,omitempty"`
+	// Slot is where a cosmetic item is worn
+	Slot Slot `json:"slot,omitempty"`
+}
+
+// Consumable reports whether using the item uses it up and changes the pet's stats
+func (it Item) Consumable() bool {
+	return it.Category != ItemCosmetic
+}
+
+// UseItem applies a consumable item's stat effects and XP
+func (s *State) UseItem(it Item, now time.Time) {
+	for stat, delta := range it.Effects {
+		s.Add(stat, delta, now)
+	}
+	s.AddXP(it.XP)
+}
+
+// ItemCatalog holds the loaded item definitions
+type ItemCatalog struct {
+	byID  map[string]Item
+	order []Item
+}
+
+// ParseItems parses and validates a JSON array of item definitions
+func ParseItems(data []byte) (*ItemCatalog, error) {
+	var list []Item
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("parse items: %w", err)
+	}
+
+	cat := &ItemCatalog{byID: make(map[string]Item, len(list))}
+	for _, it := range list {
+		if it.ID == "" {
+			return nil, fmt.Errorf("item without id")
+		}
+		if _, dup := cat.byID[it.ID]; dup {
+			return nil, fmt.Errorf("duplicate item %q", it.ID)
+		}
+		switch it.Category {
+		case ItemFood, ItemToy, ItemCare, ItemCosmetic:
+		default:
+			return nil, fmt.Errorf("item %q: unknown category %q", it.ID, it.Category)
+		}
+		if (it.Category == ItemCosmetic) != ValidSlot(it.Slot) {
+			return nil, fmt.Errorf("item %q: only cosmetics have a slot and every cosmetic needs one", it.ID)
+		}
+		if it.Price < 0 {
+			return nil, fmt.Errorf("item %q: negative price", it.ID)
+		}
+		cat.byID[it.ID] = it
+		cat.order = append(cat.order, it)
+	}
+	return cat, nil
+}
+
+// LoadItemsFile reads item definitions from path
+func LoadItemsFile(path string) (*ItemCatalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseItems(data)
+}
+
+var (
+	itemsMu sync.RWMutex
+	items   *ItemCatalog
+)
+
+func init() {
+	cat, err := ParseItems(embeddedItems)
+	if err != nil {
+		panic(err)
+	}
+	items = cat
+}
+
+// UseItems replaces the item catalogue used by the package
+func UseItems(cat *ItemCatalog) {
+	itemsMu.Lock()
+	defer itemsMu.Unlock()
+	items = cat
+}
+
+// LookupItem returns the item with the given id
+func LookupItem(id string) (Item, bool) {
+	itemsMu.RLock()
+	defer itemsMu.RUnlock()
+	it, ok := items.byID[id]
+	return it, ok
+}
+
+// AllItems returns every item in catalogue order
+func AllItems() []Item {
+	itemsMu.RLock()
+	defer itemsMu.RUnlock()
+	return items.order
+}
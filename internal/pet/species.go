@@ -0,0 +1,201 @@
+package pet
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultSpeciesID is used for pets whose species has no definition
+const DefaultSpeciesID = "default"
+
+//go:embed species.json
+var embeddedSpecies []byte
+
+// Stage is one evolution stage of a species. A pet moves to the next stage
+// once it has MinXP experience and every stat in Requires is at least the given value.
+type Stage struct {
+	ID       string       `json:"id"`
+	Name     string       `json:"name"`
+	MinXP    int          `json:"min_xp"`
+	Requires map[Stat]int `json:"requires,omitempty"`
+}
+
+// Species describes the behaviour shared by every pet of that species
+type Species struct {
+	ID           string         `json:"id"`
+	Name         string         `json:"name"`
+	BaseStats    map[Stat]int   `json:"base_stats"`
+	Decay        map[Stat]Decay `json:"decay"`
+	AllowedItems []string       `json:"allowed_items"`
+	Stages       []Stage        `json:"stages"`
+}
+
+// AllowsItem reports whether the species can be given the item
+func (sp *Species) AllowsItem(itemID string) bool {
+	for _, id := range sp.AllowedItems {
+		if id == itemID {
+			return true
+		}
+	}
+	return false
+}
+
+// Evolve advances s through every stage whose conditions are met and returns
+// one event per stage reached. A pet without a stage is placed in the first one.
+func (sp *Species) Evolve(s *State, now time.Time) []Event {
+	if len(sp.Stages) == 0 {
+		return nil
+	}
+
+	current := -1
+	for i, st := range sp.Stages {
+		if st.ID == s.Stage {
+			current = i
+			break
+		}
+	}
+	if current < 0 {
+		current = 0
+		s.Stage = sp.Stages[0].ID
+	}
+
+	var events []Event
+	for current+1 < len(sp.Stages) {
+		next := sp.Stages[current+1]
+		if !next.reachedBy(s) {
+			break
+		}
+		events = append(events, Event{
+			Type:      EventEvolved,
+			From:      s.Stage,
+			To:        next.ID,
+			Level:     s.Level,
+			CreatedAt: now,
+		})
+		s.Stage = next.ID
+		current++
+	}
+	return events
+}
+
+func (st Stage) reachedBy(s *State) bool {
+	if s.XP < st.MinXP {
+		return false
+	}
+	for stat, min := range st.Requires {
+		if s.Get(stat) < min {
+			return false
+		}
+	}
+	return true
+}
+
+// SpeciesRegistry holds the loaded species definitions
+type SpeciesRegistry struct {
+	byID  map[string]*Species
+	order []*Species
+}
+
+// ParseSpecies parses and validates a JSON array of species definitions.
+// Stats or decay rates a species leaves out fall back to DefaultStats and DefaultDecay.
+func ParseSpecies(data []byte) (*SpeciesRegistry, error) {
+	var list []*Species
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("parse species: %w", err)
+	}
+
+	reg := &SpeciesRegistry{byID: make(map[string]*Species, len(list))}
+	for _, sp := range list {
+		if sp.ID == "" {
+			return nil, fmt.Errorf("species without id")
+		}
+		if _, dup := reg.byID[sp.ID]; dup {
+			return nil, fmt.Errorf("duplicate species %q", sp.ID)
+		}
+		if sp.BaseStats == nil {
+			sp.BaseStats = make(map[Stat]int)
+		}
+		if sp.Decay == nil {
+			sp.Decay = make(map[Stat]Decay)
+		}
+		for _, stat := range AllStats {
+			if _, ok := sp.BaseStats[stat]; !ok {
+				sp.BaseStats[stat] = DefaultStats[stat]
+			}
+			if _, ok := sp.Decay[stat]; !ok {
+				sp.Decay[stat] = DefaultDecay[stat]
+			}
+		}
+		for i := 1; i < len(sp.Stages); i++ {
+			if sp.Stages[i].MinXP < sp.Stages[i-1].MinXP {
+				return nil, fmt.Errorf("species %q: stage %q needs less XP than the one before it", sp.ID, sp.Stages[i].ID)
+			}
+		}
+		reg.byID[sp.ID] = sp
+		reg.order = append(reg.order, sp)
+	}
+	if _, ok := reg.byID[DefaultSpeciesID]; !ok {
+		return nil, fmt.Errorf("species %q must be defined", DefaultSpeciesID)
+	}
+	return reg, nil
+}
+
+// LoadSpeciesFile reads species definitions from path
+func LoadSpeciesFile(path string) (*SpeciesRegistry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSpecies(data)
+}
+
+// Get returns the species with the given id, or the default species
+func (r *SpeciesRegistry) Get(id string) *Species {
+	if sp, ok := r.byID[id]; ok {
+		return sp
+	}
+	return r.byID[DefaultSpeciesID]
+}
+
+// All returns the species in definition order
+func (r *SpeciesRegistry) All() []*Species {
+	return r.order
+}
+
+var (
+	speciesMu sync.RWMutex
+	species   *SpeciesRegistry
+)
+
+func init() {
+	reg, err := ParseSpecies(embeddedSpecies)
+	if err != nil {
+		panic(err)
+	}
+	species = reg
+}
+
+// UseSpecies replaces the species definitions used by the package
+func UseSpecies(reg *SpeciesRegistry) {
+	speciesMu.Lock()
+	defer speciesMu.Unlock()
+	species = reg
+}
+
+// LookupSpecies returns the definition for a species id, falling back to the default species
+func LookupSpecies(id string) *Species {
+	speciesMu.RLock()
+	defer speciesMu.RUnlock()
+	return species.Get(id)
+}
+
+// AllSpecies returns every loaded species definition
+func AllSpecies() []*Species {
+	speciesMu.RLock()
+	defer speciesMu.RUnlock()
+	return species.All()
+}
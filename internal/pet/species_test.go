@@ -0,0 +1,113 @@
+package pet
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSpecies(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		wantErr string // Empty if parsing should succeed
+	}{
+		{"embedded definitions", string(embeddedSpecies), ""},
+		{"minimal default", `[{"id": "default"}]`, ""},
+		{"invalid json", `[{"id": }]`, "parse species"},
+		{"missing id", `[{"id": "default"}, {"name": "Nameless"}]`, "without id"},
+		{"duplicate id", `[{"id": "default"}, {"id": "default"}]`, "duplicate species"},
+		{"no default species", `[{"id": "cat"}]`, `"default" must be defined`},
+		{"bad decay interval", `[{"id": "default", "decay": {"hunger": {"points": 1, "every": "soon"}}}]`, "decay interval"},
+		{
+			"stages out of XP order",
+			`[{"id": "default", "stages": [{"id": "a", "min_xp": 100}, {"id": "b", "min_xp": 50}]}]`,
+			"needs less XP",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSpecies([]byte(tt.json))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ParseSpecies() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseSpecies() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseSpeciesDefaults(t *testing.T) {
+	reg, err := ParseSpecies([]byte(`[{"id": "default", "base_stats": {"hunger": 90}, "decay": {"hygiene": {"points": 3, "every": "1h"}}}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp := reg.Get("unknown")
+	if sp.ID != DefaultSpeciesID {
+		t.Fatalf("Get(unknown) = %q, want the default species", sp.ID)
+	}
+	if sp.BaseStats[StatHunger] != 90 || sp.BaseStats[StatHygiene] != DefaultStats[StatHygiene] {
+		t.Errorf("base stats = %v, want hunger 90 and the default for the rest", sp.BaseStats)
+	}
+	if sp.Decay[StatHygiene] != (Decay{Points: 3, Every: time.Hour}) || sp.Decay[StatHunger] != DefaultDecay[StatHunger] {
+		t.Errorf("decay = %v, want hygiene 3/1h and the default for the rest", sp.Decay)
+	}
+}
+
+func TestEvolve(t *testing.T) {
+	sp := &Species{Stages: []Stage{
+		{ID: "egg", MinXP: 0},
+		{ID: "baby", MinXP: 100},
+		{ID: "teen", MinXP: 600, Requires: map[Stat]int{StatHappiness: 40}},
+		{ID: "adult", MinXP: 2100},
+	}}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		stage     string
+		xp        int
+		happiness int
+		want      string
+		wantSteps []string // Stages reached, in order
+	}{
+		{"new pet is placed in the first stage", "", 0, 50, "egg", nil},
+		{"unknown stage restarts from the first", "gone", 0, 50, "egg", nil},
+		{"not enough XP", "egg", 99, 50, "egg", nil},
+		{"one stage", "egg", 100, 50, "baby", []string{"baby"}},
+		{"several stages at once", "egg", 700, 50, "teen", []string{"baby", "teen"}},
+		{"stat requirement not met", "baby", 5000, 39, "baby", nil},
+		{"requirements met later stages follow", "baby", 5000, 40, "adult", []string{"teen", "adult"}},
+		{"last stage stays", "adult", 9000, 100, "adult", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := State{Stage: tt.stage, XP: tt.xp, Happiness: tt.happiness}
+			events := sp.Evolve(&s, now)
+			if s.Stage != tt.want {
+				t.Errorf("stage = %q, want %q", s.Stage, tt.want)
+			}
+			if len(events) != len(tt.wantSteps) {
+				t.Fatalf("got %d events, want %d", len(events), len(tt.wantSteps))
+			}
+			from := tt.stage
+			for i, e := range events {
+				if e.Type != EventEvolved || e.From != from || e.To != tt.wantSteps[i] || !e.CreatedAt.Equal(now) {
+					t.Errorf("event %d = %+v, want %s from %q to %q", i, e, EventEvolved, from, tt.wantSteps[i])
+				}
+				from = e.To
+			}
+		})
+	}
+}
+
+func TestEvolveWithoutStages(t *testing.T) {
+	s := State{XP: 5000}
+	if events := (&Species{}).Evolve(&s, time.Now()); events != nil || s.Stage != "" {
+		t.Errorf("Evolve() = %v, stage %q, want nothing for a species without stages", events, s.Stage)
+	}
+}
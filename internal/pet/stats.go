@@ -0,0 +1,189 @@
+package pet
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Stat identifies one of the pet's decaying care stats.
+// Every stat is kept in the 0-100 range where 100 is the best state
+// (a hunger of 100 means the pet is completely full).
+type Stat string
+
+const (
+	StatHunger    Stat = "hunger"
+	StatHygiene   Stat = "hygiene"
+	StatHappiness Stat = "happiness"
+)
+
+// AllStats lists the decaying stats in display order
+var AllStats = []Stat{StatHunger, StatHygiene, StatHappiness}
+
+const (
+	StatMin = 0
+	StatMax = 100
+
+	// XPPerLevel is the XP step between levels: reaching level n needs
+	// XPPerLevel * n * (n-1) / 2 total XP (0, 100, 300, 600, ...)
+	XPPerLevel = 100
+)
+
+// Decay describes how a stat drains over time: Points are removed for every
+// full Every interval since the stat was last updated.
+type Decay struct {
+	Points int
+	Every  time.Duration
+}
+
+type decayJSON struct {
+	Points int    `json:"points"`
+	Every  string `json:"every"`
+}
+
+// MarshalJSON encodes the interval as a duration string such as "15m"
+func (d Decay) MarshalJSON() ([]byte, error) {
+	return json.Marshal(decayJSON{Points: d.Points, Every: d.Every.String()})
+}
+
+// UnmarshalJSON decodes {"points": 1, "every": "15m"}
+func (d *Decay) UnmarshalJSON(data []byte) error {
+	var raw decayJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	every, err := time.ParseDuration(raw.Every)
+	if err != nil {
+		return fmt.Errorf("decay interval %q: %w", raw.Every, err)
+	}
+	d.Points = raw.Points
+	d.Every = every
+	return nil
+}
+
+// DefaultStats are the starting values used when a species does not define its own
+var DefaultStats = map[Stat]int{
+	StatHunger:    70,
+	StatHygiene:   70,
+	StatHappiness: 50,
+}
+
+// DefaultDecay is the drain used when a species does not define its own.
+// Happiness keeps the original mood drain of -1 every 25 minutes.
+var DefaultDecay = map[Stat]Decay{
+	StatHunger:    {Points: 1, Every: 15 * time.Minute},
+	StatHygiene:   {Points: 1, Every: 30 * time.Minute},
+	StatHappiness: {Points: 1, Every: 25 * time.Minute},
+}
+
+// State is the typed view of a pet's stats at a point in time
+type State struct {
+	Stage       string `json:"stage"`
+	Hunger      int    `json:"hunger"`
+	Hygiene     int    `json:"hygiene"`
+	Happiness   int    `json:"happiness"`
+	XP          int    `json:"xp"`
+	Level       int    `json:"level"`
+	NextLevelXP int    `json:"next_level_xp"`
+
+	// LastUpdated holds the time each stat was last decayed or changed
+	LastUpdated map[Stat]time.Time `json:"last_updated"`
+
+	// AsleepUntil is set while the pet is sleeping
+	AsleepUntil *time.Time `json:"asleep_until,omitempty"`
+	// Cooldowns holds the time each care action can be used again
+	Cooldowns map[string]time.Time `json:"cooldowns,omitempty"`
+
+	// Events lists what happened during the update that produced this state
+	Events []Event `json:"events,omitempty"`
+}
+
+// Get returns the value of a stat
+func (s *State) Get(stat Stat) int {
+	if p := s.field(stat); p != nil {
+		return *p
+	}
+	return 0
+}
+
+// Add changes a stat by delta (clamped to 0-100) and marks it updated at now
+func (s *State) Add(stat Stat, delta int, now time.Time) {
+	p := s.field(stat)
+	if p == nil {
+		return
+	}
+	*p = clamp(*p + delta)
+	s.LastUpdated[stat] = now
+}
+
+// AddXP adds experience and recomputes the level
+func (s *State) AddXP(amount int) {
+	s.XP += amount
+	if s.XP < 0 {
+		s.XP = 0
+	}
+	s.Level, s.NextLevelXP = LevelForXP(s.XP)
+}
+
+func (s *State) field(stat Stat) *int {
+	switch stat {
+	case StatHunger:
+		return &s.Hunger
+	case StatHygiene:
+		return &s.Hygiene
+	case StatHappiness:
+		return &s.Happiness
+	}
+	return nil
+}
+
+// ApplyDecay drains every stat for the time elapsed since it was last updated.
+// Only whole intervals are consumed so partial progress towards the next point
+// is kept. Returns true if any stat changed.
+func (s *State) ApplyDecay(decay map[Stat]Decay, now time.Time) bool {
+	changed := false
+	for _, stat := range AllStats {
+		d, ok := decay[stat]
+		if !ok || d.Points <= 0 || d.Every <= 0 {
+			continue
+		}
+		last := s.LastUpdated[stat]
+		if !now.After(last) {
+			continue
+		}
+		intervals := int(now.Sub(last) / d.Every)
+		if intervals == 0 {
+			continue
+		}
+
+		p := s.field(stat)
+		*p = clamp(*p - intervals*d.Points)
+		if *p == StatMin {
+			// Nothing left to drain, restart the clock from now
+			s.LastUpdated[stat] = now
+		} else {
+			s.LastUpdated[stat] = last.Add(time.Duration(intervals) * d.Every)
+		}
+		changed = true
+	}
+	return changed
+}
+
+// LevelForXP returns the level reached with xp and the total XP needed for the next level
+func LevelForXP(xp int) (level, nextLevelXP int) {
+	level = 1
+	for xp >= XPPerLevel*(level+1)*level/2 {
+		level++
+	}
+	return level, XPPerLevel * (level + 1) * level / 2
+}
+
+func clamp(v int) int {
+	if v < StatMin {
+		return StatMin
+	}
+	if v > StatMax {
+		return StatMax
+	}
+	return v
+}
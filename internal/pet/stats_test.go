@@ -0,0 +1,76 @@
+package pet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApplyDecay(t *testing.T) {
+	last := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	hunger := map[Stat]Decay{StatHunger: {Points: 2, Every: 15 * time.Minute}}
+
+	tests := []struct {
+		name        string
+		start       int
+		decay       map[Stat]Decay
+		elapsed     time.Duration
+		want        int
+		wantLast    time.Duration // LastUpdated after the call, relative to last
+		wantChanged bool
+	}{
+		{"under one interval", 50, hunger, 14 * time.Minute, 50, 0, false},
+		{"one interval", 50, hunger, 15 * time.Minute, 48, 15 * time.Minute, true},
+		{"partial interval is kept", 50, hunger, 40 * time.Minute, 46, 30 * time.Minute, true},
+		{"many intervals", 50, hunger, 3 * time.Hour, 26, 3 * time.Hour, true},
+		// Restarts from now rather than from the last whole interval
+		{"clamped at zero restarts the clock", 5, hunger, 2*time.Hour + time.Minute, 0, 2*time.Hour + time.Minute, true},
+		{"clock in the future", 50, hunger, -time.Hour, 50, 0, false},
+		{"no decay for the stat", 50, map[Stat]Decay{}, 3 * time.Hour, 50, 0, false},
+		{"zero interval is ignored", 50, map[Stat]Decay{StatHunger: {Points: 1}}, 3 * time.Hour, 50, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := State{Hunger: tt.start, LastUpdated: map[Stat]time.Time{StatHunger: last}}
+			changed := s.ApplyDecay(tt.decay, last.Add(tt.elapsed))
+			if changed != tt.wantChanged {
+				t.Errorf("ApplyDecay() = %v, want %v", changed, tt.wantChanged)
+			}
+			if s.Hunger != tt.want {
+				t.Errorf("hunger = %d, want %d", s.Hunger, tt.want)
+			}
+			if got := s.LastUpdated[StatHunger]; !got.Equal(last.Add(tt.wantLast)) {
+				t.Errorf("last updated = %v, want %v", got, last.Add(tt.wantLast))
+			}
+		})
+	}
+}
+
+func TestLevelForXP(t *testing.T) {
+	tests := []struct {
+		xp        int
+		wantLevel int
+		wantNext  int
+	}{
+		{0, 1, 100},
+		{99, 1, 100},
+		{100, 2, 300},
+		{299, 2, 300},
+		{300, 3, 600},
+		{600, 4, 1000},
+		{4500, 10, 5500},
+	}
+	for _, tt := range tests {
+		level, next := LevelForXP(tt.xp)
+		if level != tt.wantLevel || next != tt.wantNext {
+			t.Errorf("LevelForXP(%d) = %d, %d, want %d, %d", tt.xp, level, next, tt.wantLevel, tt.wantNext)
+		}
+	}
+}
+
+func TestAddXPNeverGoesNegative(t *testing.T) {
+	s := State{XP: 30}
+	s.AddXP(-50)
+	if s.XP != 0 || s.Level != 1 || s.NextLevelXP != 100 {
+		t.Errorf("AddXP(-50) left XP %d, level %d, next %d, want 0, 1, 100", s.XP, s.Level, s.NextLevelXP)
+	}
+}
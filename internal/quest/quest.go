@@ -0,0 +1,272 @@
+package quest
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"time"
+
+	"fsd-backend/internal/pet"
+)
+
+//go:embed quests.json
+var embeddedPool []byte
+
+// Periods a quest can rotate on
+const (
+	Daily  = "daily"
+	Weekly = "weekly"
+)
+
+// Periods lists every period in display order
+var Periods = []string{Daily, Weekly}
+
+// Quest types
+const (
+	// HabitCompletions counts habits marked done; reopening a habit takes one back
+	HabitCompletions = "habit_completions"
+	// GamePlays counts finished plays, of Game if set
+	GamePlays = "game_plays"
+	// BestScore is the best single score in Game
+	BestScore = "best_score"
+	// MultiplayerWins counts multiplayer wins, in Game if set
+	MultiplayerWins = "multiplayer_wins"
+	// PetCare counts care actions performed on the pet
+	PetCare = "pet_care"
+	// PetStat is the highest value of Stat seen on the user's pet
+	PetStat = "pet_stat"
+)
+
+var questTypes = map[string]bool{
+	HabitCompletions: true,
+	GamePlays:        true,
+	BestScore:        true,
+	MultiplayerWins:  true,
+	PetCare:          true,
+	PetStat:          true,
+}
+
+// Event kinds that advance quests
+const (
+	EventHabitCompleted = "habit_completed"
+	EventHabitReopened  = "habit_reopened"
+	EventGamePlayed     = "game_played"
+	EventPetCare        = "pet_care"
+	// EventPetStats carries the pet's current stats; it is produced by the
+	// tracker rather than by a user action
+	EventPetStats = "pet_stats"
+)
+
+// Event is something the user did that may advance quests
+type Event struct {
+	Kind   string
+	GameID string
+	Score  int
+	Won    bool
+	Stats  map[pet.Stat]int
+	// CompletedAt is when the completion undone by EventHabitReopened was made;
+	// only quests whose period contains it lose progress
+	CompletedAt time.Time
+}
+
+// Reward is granted once when a completed quest is claimed
+type Reward struct {
+	Coins  int `json:"coins,omitempty"`
+	Energy int `json:"energy,omitempty"`
+}
+
+// Definition describes one quest in the pool
+type Definition struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Period      string   `json:"period"`
+	Type        string   `json:"type"`
+	Game        string   `json:"game,omitempty"`
+	Stat        pet.Stat `json:"stat,omitempty"`
+	Target      int      `json:"target"`
+	Reward      Reward   `json:"reward"`
+}
+
+// Step is how an event moves a quest: progress changes by Delta and is raised
+// to at least AtLeast. The result is kept within 0 and the target.
+type Step struct {
+	Delta   int
+	AtLeast int
+}
+
+// Advance returns how ev moves the quest, or false if it does not apply
+func (d *Definition) Advance(ev Event) (Step, bool) {
+	switch d.Type {
+	case HabitCompletions:
+		switch ev.Kind {
+		case EventHabitCompleted:
+			return Step{Delta: 1}, true
+		case EventHabitReopened:
+			return Step{Delta: -1}, true
+		}
+	case GamePlays:
+		if ev.Kind == EventGamePlayed && d.matchesGame(ev.GameID) {
+			return Step{Delta: 1}, true
+		}
+	case BestScore:
+		if ev.Kind == EventGamePlayed && d.matchesGame(ev.GameID) {
+			return Step{AtLeast: ev.Score}, true
+		}
+	case MultiplayerWins:
+		if ev.Kind == EventGamePlayed && ev.Won && d.matchesGame(ev.GameID) {
+			return Step{Delta: 1}, true
+		}
+	case PetCare:
+		if ev.Kind == EventPetCare {
+			return Step{Delta: 1}, true
+		}
+	case PetStat:
+		if ev.Kind == EventPetStats {
+			return Step{AtLeast: ev.Stats[d.Stat]}, true
+		}
+	}
+	return Step{}, false
+}
+
+func (d *Definition) matchesGame(gameID string) bool {
+	return d.Game == "" || d.Game == gameID
+}
+
+// Bounds returns the period containing now. Days start at midnight UTC and
+// weeks on Monday, matching the leaderboard windows.
+func Bounds(period string, now time.Time) (start, end time.Time) {
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if period == Weekly {
+		// time.Weekday starts on Sunday; shift so the week starts on Monday
+		monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+		return monday, monday.AddDate(0, 0, 7)
+	}
+	return today, today.AddDate(0, 0, 1)
+}
+
+// Pool holds the loaded quest definitions and how many of each period a user gets
+type Pool struct {
+	DailyCount  int           `json:"daily_count"`
+	WeeklyCount int           `json:"weekly_count"`
+	Quests      []*Definition `json:"quests"`
+
+	byID map[string]*Definition
+}
+
+// Parse parses and validates a quest pool
+func Parse(data []byte) (*Pool, error) {
+	var p Pool
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("parse quests: %w", err)
+	}
+	if p.DailyCount < 0 || p.WeeklyCount < 0 {
+		return nil, fmt.Errorf("quest counts must not be negative")
+	}
+
+	p.byID = make(map[string]*Definition, len(p.Quests))
+	for _, d := range p.Quests {
+		if d.ID == "" {
+			return nil, fmt.Errorf("quest without id")
+		}
+		if _, dup := p.byID[d.ID]; dup {
+			return nil, fmt.Errorf("duplicate quest %q", d.ID)
+		}
+		if d.Period != Daily && d.Period != Weekly {
+			return nil, fmt.Errorf("quest %q: period must be %s or %s", d.ID, Daily, Weekly)
+		}
+		if !questTypes[d.Type] {
+			return nil, fmt.Errorf("quest %q: unknown type %q", d.ID, d.Type)
+		}
+		if d.Target <= 0 {
+			return nil, fmt.Errorf("quest %q: target must be positive", d.ID)
+		}
+		if d.Type == BestScore && d.Game == "" {
+			return nil, fmt.Errorf("quest %q: %s needs a game", d.ID, BestScore)
+		}
+		if d.Type == PetStat && d.Stat == "" {
+			return nil, fmt.Errorf("quest %q: %s needs a stat", d.ID, PetStat)
+		}
+		if d.Reward.Coins < 0 || d.Reward.Energy < 0 || d.Reward == (Reward{}) {
+			return nil, fmt.Errorf("quest %q: reward must grant coins or energy", d.ID)
+		}
+		p.byID[d.ID] = d
+	}
+	return &p, nil
+}
+
+// LoadFile reads a quest pool from path
+func LoadFile(path string) (*Pool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Pick returns the quests userID gets for the period starting at start.
+// The choice is a deterministic shuffle seeded by the user and period, so it
+// is stable across requests while differing between users and periods.
+func (p *Pool) Pick(userID, period string, start time.Time) []*Definition {
+	n := p.DailyCount
+	if period == Weekly {
+		n = p.WeeklyCount
+	}
+	var candidates []*Definition
+	for _, d := range p.Quests {
+		if d.Period == period {
+			candidates = append(candidates, d)
+		}
+	}
+	if n > len(candidates) {
+		n = len(candidates)
+	}
+
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s|%s|%s", userID, period, start.Format(time.DateOnly))
+	rng := rand.New(rand.NewPCG(h.Sum64(), 0))
+	rng.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	return candidates[:n]
+}
+
+var (
+	poolMu sync.RWMutex
+	pool   *Pool
+)
+
+func init() {
+	p, err := Parse(embeddedPool)
+	if err != nil {
+		panic(err)
+	}
+	pool = p
+}
+
+// Use replaces the quest pool used by the package
+func Use(p *Pool) {
+	poolMu.Lock()
+	defer poolMu.Unlock()
+	pool = p
+}
+
+// Lookup returns the quest with the given id
+func Lookup(id string) (*Definition, bool) {
+	poolMu.RLock()
+	defer poolMu.RUnlock()
+	d, ok := pool.byID[id]
+	return d, ok
+}
+
+// Pick returns the quests userID gets for the period starting at start
+func Pick(userID, period string, start time.Time) []*Definition {
+	poolMu.RLock()
+	defer poolMu.RUnlock()
+	return pool.Pick(userID, period, start)
+}
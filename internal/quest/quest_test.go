@@ -0,0 +1,35 @@
+package quest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBounds(t *testing.T) {
+	utc := func(y int, m time.Month, d, h int) time.Time { return time.Date(y, m, d, h, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name      string
+		period    string
+		now       time.Time
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{"daily", Daily, utc(2026, 10, 18, 15), utc(2026, 10, 18, 0), utc(2026, 10, 19, 0)},
+		{"daily at midnight", Daily, utc(2026, 10, 18, 0), utc(2026, 10, 18, 0), utc(2026, 10, 19, 0)},
+		{"daily uses the UTC date", Daily, time.Date(2026, 10, 19, 1, 0, 0, 0, time.FixedZone("CEST", 2*60*60)), utc(2026, 10, 18, 0), utc(2026, 10, 19, 0)},
+		{"daily across a year", Daily, utc(2026, 12, 31, 23), utc(2026, 12, 31, 0), utc(2027, 1, 1, 0)},
+		{"weekly mid-week", Weekly, utc(2026, 10, 14, 12), utc(2026, 10, 12, 0), utc(2026, 10, 19, 0)},
+		{"weekly on Monday", Weekly, utc(2026, 10, 12, 0), utc(2026, 10, 12, 0), utc(2026, 10, 19, 0)},
+		{"weekly on Sunday", Weekly, utc(2026, 10, 18, 23), utc(2026, 10, 12, 0), utc(2026, 10, 19, 0)},
+		{"weekly across a month", Weekly, utc(2026, 11, 1, 9), utc(2026, 10, 26, 0), utc(2026, 11, 2, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := Bounds(tt.period, tt.now)
+			if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Errorf("Bounds() = %v, %v, want %v, %v", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
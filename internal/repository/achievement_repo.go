@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AchievementRepo stores which achievements each user has unlocked
+type AchievementRepo struct{ db *pgxpool.Pool }
+
+func NewAchievementRepo(db *pgxpool.Pool) *AchievementRepo { return &AchievementRepo{db: db} }
+
+// Unlocked returns the unlock time of every achievement the user has
+func (r *AchievementRepo) Unlocked(ctx context.Context, userID string) (map[string]time.Time, error) {
+	rows, err := r.db.Query(ctx, `SELECT achievement_id, unlocked_at FROM user_achievements WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[string]time.Time{}
+	for rows.Next() {
+		var id string
+		var at time.Time
+		if err := rows.Scan(&id, &at); err != nil {
+			return nil, err
+		}
+		out[id] = at
+	}
+	return out, rows.Err()
+}
+
+// Unlock records the achievements and returns the unlock time of those that
+// were not already unlocked. Repeated unlocks are ignored.
+func (r *AchievementRepo) Unlock(ctx context.Context, userID string, ids []string) (map[string]time.Time, error) {
+	const q = `
+INSERT INTO user_achievements (user_id, achievement_id)
+SELECT $1::UUID, unnest($2::STRING[])
+ON CONFLICT (user_id, achievement_id) DO NOTHING
+RETURNING achievement_id, unlocked_at`
+	rows, err := r.db.Query(ctx, q, userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[string]time.Time{}
+	for rows.Next() {
+		var id string
+		var at time.Time
+		if err := rows.Scan(&id, &at); err != nil {
+			return nil, err
+		}
+		out[id] = at
+	}
+	return out, rows.Err()
+}
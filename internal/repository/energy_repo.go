@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"fsd-backend/internal/energy"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// EnergyMax is the most energy a user can hold (new users start with 30)
+const EnergyMax = energy.Max
+
+// Reasons recorded in the energy ledger
+const (
+	EnergyReasonGameSession    = "game_session"
+	EnergyReasonGame           = "game" // Replaced by game_session; kept for existing entries
+	EnergyReasonPetCare        = "pet_care"
+	EnergyReasonHabitCompleted = "habit_completed"
+	EnergyReasonHabitReopened  = "habit_reopened"
+	EnergyReasonRegen          = "regen"
+	EnergyReasonDailyBonus     = "daily_bonus" // Replaced by daily_login; kept for existing entries
+	EnergyReasonDailyLogin     = "daily_login"
+	EnergyReasonQuest          = "quest"
+	EnergyReasonAdmin          = "admin"
+)
+
+var (
+	// ErrInsufficientEnergy is returned when a debit would take energy below zero
+	ErrInsufficientEnergy = errors.New("insufficient energy")
+	// ErrAlreadyGranted is returned when a one-off grant was already given
+	ErrAlreadyGranted = errors.New("energy already granted")
+)
+
+// energyChange describes the ledger entry written for a change
+type energyChange struct {
+	Reason  string
+	RefID   string
+	Note    string
+	ActorID string
+	// Record writes the entry even if the balance did not move (e.g. a grant at the cap)
+	Record bool
+}
+
+// EnergyRepo owns users.energy. Passive regeneration is applied lazily from the
+// energy_regen_at clock whenever energy is read or changed, and every change is
+// recorded in energy_ledger within the same transaction.
+type EnergyRepo struct{ db *pgxpool.Pool }
+
+func NewEnergyRepo(db *pgxpool.Pool) *EnergyRepo { return &EnergyRepo{db: db} }
+
+// Get returns the user's current energy including regeneration
+func (r *EnergyRepo) Get(ctx context.Context, userID string) (int, error) {
+	s, err := r.GetStatus(ctx, userID)
+	return s.Energy, err
+}
+
+// GetStatus returns the user's current energy with regeneration countdowns.
+// Nothing is written; the regenerated points are stored by the next change.
+func (r *EnergyRepo) GetStatus(ctx context.Context, userID string) (energy.Status, error) {
+	var value int
+	var regenAt time.Time
+	err := r.db.QueryRow(ctx, `SELECT energy, energy_regen_at FROM users WHERE id = $1`, userID).Scan(&value, &regenAt)
+	if err != nil {
+		return energy.Status{}, err
+	}
+	return energy.CurrentRegen().Status(value, regenAt, time.Now()), nil
+}
+
+// SpendTx deducts amount inside tx and returns the new balance.
+// Fails with ErrInsufficientEnergy without changing anything if the user has less than amount.
+func (r *EnergyRepo) SpendTx(ctx context.Context, tx pgx.Tx, userID string, amount int, reason, refID string) (int, error) {
+	change := energyChange{Reason: reason, RefID: refID}
+	return r.adjust(ctx, tx, userID, change, func(current int) (int, error) {
+		if current < amount {
+			return 0, ErrInsufficientEnergy
+		}
+		return current - amount, nil
+	})
+}
+
+// AddTx credits (or, for a negative delta, debits) energy inside tx and returns
+// the new balance. The result is clamped to 0-EnergyMax instead of failing;
+// the ledger records the change actually applied.
+func (r *EnergyRepo) AddTx(ctx context.Context, tx pgx.Tx, userID string, delta int, reason, refID string) (int, error) {
+	change := energyChange{Reason: reason, RefID: refID}
+	return r.adjust(ctx, tx, userID, change, addClamped(delta))
+}
+
+// GrantTx credits amount (clamped to EnergyMax) inside tx and records the grant
+// even if nothing was added, so LastGrantTx always finds what it gave
+func (r *EnergyRepo) GrantTx(ctx context.Context, tx pgx.Tx, userID string, amount int, reason, refID string) (int, error) {
+	change := energyChange{Reason: reason, RefID: refID, Record: true}
+	return r.adjust(ctx, tx, userID, change, addClamped(amount))
+}
+
+// LastGrantTx returns the energy actually added by the user's latest ledger
+// entry with reason and refID, or 0 if there is none
+func (r *EnergyRepo) LastGrantTx(ctx context.Context, tx pgx.Tx, userID, reason, refID string) (int, error) {
+	const q = `SELECT delta FROM energy_ledger WHERE user_id = $1 AND reason = $2 AND ref_id = $3
+	           ORDER BY created_at DESC, id DESC LIMIT 1`
+	var delta int
+	err := tx.QueryRow(ctx, q, userID, reason, refID).Scan(&delta)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	return delta, err
+}
+
+// GrantOnceTx credits amount inside tx unless the user already received a grant
+// with the same reason and refID, in which case it fails with ErrAlreadyGranted.
+// The grant is recorded even if the user is at EnergyMax so it can't be claimed again.
+func (r *EnergyRepo) GrantOnceTx(ctx context.Context, tx pgx.Tx, userID string, amount int, reason, refID string) (int, error) {
+	change := energyChange{Reason: reason, RefID: refID, Record: true}
+	return r.adjust(ctx, tx, userID, change, func(current int) (int, error) {
+		var granted bool
+		const q = `SELECT EXISTS (SELECT 1 FROM energy_ledger WHERE user_id = $1 AND reason = $2 AND ref_id = $3)`
+		if err := tx.QueryRow(ctx, q, userID, reason, refID).Scan(&granted); err != nil {
+			return 0, err
+		}
+		if granted {
+			return 0, ErrAlreadyGranted
+		}
+		return clampEnergy(current + amount), nil
+	})
+}
+
+// AdminAdjustTx applies a manual correction of delta (clamped to 0-EnergyMax) made
+// by actorID, recording note as the justification
+func (r *EnergyRepo) AdminAdjustTx(ctx context.Context, tx pgx.Tx, userID string, delta int, note, actorID string) (int, error) {
+	change := energyChange{Reason: EnergyReasonAdmin, Note: note, ActorID: actorID, Record: true}
+	return r.adjust(ctx, tx, userID, change, addClamped(delta))
+}
+
+func addClamped(delta int) func(int) (int, error) {
+	return func(current int) (int, error) {
+		return clampEnergy(current + delta), nil
+	}
+}
+
+// adjust locks the user's row, applies pending regeneration, then fn, and
+// writes the result with ledger entries for the regenerated points and the change.
+// The row lock serialises concurrent changes for the same user.
+func (r *EnergyRepo) adjust(ctx context.Context, tx pgx.Tx, userID string, change energyChange, fn func(current int) (int, error)) (int, error) {
+	var before int
+	var regenAt time.Time
+	const sel = `SELECT energy, energy_regen_at FROM users WHERE id = $1 FOR UPDATE`
+	if err := tx.QueryRow(ctx, sel, userID).Scan(&before, &regenAt); err != nil {
+		return 0, err
+	}
+
+	regenerated, regenAt := energy.CurrentRegen().Apply(before, regenAt, time.Now())
+	after, err := fn(regenerated)
+	if err != nil {
+		return 0, err
+	}
+
+	const upd = `UPDATE users SET energy = $2, energy_regen_at = $3, updated_at = now() WHERE id = $1`
+	if _, err := tx.Exec(ctx, upd, userID, after, regenAt); err != nil {
+		return 0, err
+	}
+	if regenerated != before {
+		regen := energyChange{Reason: EnergyReasonRegen}
+		if err := insertLedger(ctx, tx, userID, regenerated-before, regenerated, regen); err != nil {
+			return 0, err
+		}
+	}
+	if after != regenerated || change.Record {
+		if err := insertLedger(ctx, tx, userID, after-regenerated, after, change); err != nil {
+			return 0, err
+		}
+	}
+	return after, nil
+}
+
+func insertLedger(ctx context.Context, tx pgx.Tx, userID string, delta, balance int, change energyChange) error {
+	const q = `
+INSERT INTO energy_ledger (user_id, delta, reason, ref_id, note, actor_id, balance_after)
+VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := tx.Exec(ctx, q, userID, delta, change.Reason,
+		nullString(change.RefID), nullString(change.Note), nullString(change.ActorID), balance)
+	return err
+}
+
+// nullString maps "" to NULL
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func clampEnergy(v int) int {
+	if v < 0 {
+		return 0
+	}
+	if v > EnergyMax {
+		return EnergyMax
+	}
+	return v
+}
+
+// History returns the user's ledger entries newest first. Pass the CreatedAt and
+// ID of the last entry of the previous page to continue after it.
+func (r *EnergyRepo) History(ctx context.Context, userID string, afterCreatedAt *time.Time, afterID string, limit int) ([]EnergyLedgerEntry, error) {
+	const q = `SELECT id, delta, reason, ref_id, note, actor_id, balance_after, created_at FROM energy_ledger
+WHERE user_id = $1
+  AND ($2::TIMESTAMPTZ IS NULL OR (created_at, id) < ($2, $3::UUID))
+ORDER BY created_at DESC, id DESC
+LIMIT $4`
+	var cursorID any
+	if afterCreatedAt != nil {
+		cursorID = afterID
+	}
+	rows, err := r.db.Query(ctx, q, userID, afterCreatedAt, cursorID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []EnergyLedgerEntry{}
+	for rows.Next() {
+		var e EnergyLedgerEntry
+		if err := rows.Scan(&e.ID, &e.Delta, &e.Reason, &e.RefID, &e.Note, &e.ActorID, &e.BalanceAfter, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"fsd-backend/internal/game"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	PlayModeSolo        = "solo"
+	PlayModeMultiplayer = "multiplayer"
+
+	OutcomeWin  = game.OutcomeWin
+	OutcomeLoss = game.OutcomeLoss
+	OutcomeDraw = game.OutcomeDraw
+)
+
+// GamePlayRepo stores every individual game result
+type GamePlayRepo struct{ db *pgxpool.Pool }
+
+func NewGamePlayRepo(db *pgxpool.Pool) *GamePlayRepo { return &GamePlayRepo{db: db} }
+
+const gamePlayColumns = `id, user_id, game_id, score, duration_ms, mode, opponent_id, outcome, session_id, match_id, started_at, ended_at`
+
+// Record stores a play; ID is filled in on p
+func (r *GamePlayRepo) Record(ctx context.Context, p *GamePlay) error {
+	return r.record(ctx, r.db, p)
+}
+
+// RecordTx is Record inside a caller-managed transaction
+func (r *GamePlayRepo) RecordTx(ctx context.Context, tx pgx.Tx, p *GamePlay) error {
+	return r.record(ctx, tx, p)
+}
+
+func (r *GamePlayRepo) record(ctx context.Context, q querier, p *GamePlay) error {
+	if p.Mode == "" {
+		p.Mode = PlayModeSolo
+	}
+	const sql = `
+INSERT INTO game_plays (user_id, game_id, score, duration_ms, mode, opponent_id, outcome, session_id, match_id, started_at, ended_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING id`
+	return q.QueryRow(ctx, sql, p.UserID, p.GameID, p.Score, p.DurationMs, p.Mode,
+		p.OpponentID, p.Outcome, p.SessionID, p.MatchID, p.StartedAt, p.EndedAt).Scan(&p.ID)
+}
+
+// History returns the user's plays newest first. gameID may be empty for all games.
+// Pass the EndedAt and ID of the last play of the previous page to continue after it.
+func (r *GamePlayRepo) History(ctx context.Context, userID, gameID string, afterEndedAt *time.Time, afterID string, limit int) ([]GamePlay, error) {
+	const q = `SELECT ` + gamePlayColumns + ` FROM game_plays
+WHERE user_id = $1
+  AND ($2 = '' OR game_id = $2)
+  AND ($3::TIMESTAMPTZ IS NULL OR (ended_at, id) < ($3, $4::UUID))
+ORDER BY ended_at DESC, id DESC
+LIMIT $5`
+	var cursorID any
+	if afterEndedAt != nil {
+		cursorID = afterID
+	}
+	rows, err := r.db.Query(ctx, q, userID, gameID, afterEndedAt, cursorID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []GamePlay{}
+	for rows.Next() {
+		var p GamePlay
+		if err := rows.Scan(&p.ID, &p.UserID, &p.GameID, &p.Score, &p.DurationMs, &p.Mode,
+			&p.OpponentID, &p.Outcome, &p.SessionID, &p.MatchID, &p.StartedAt, &p.EndedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// Stats returns per-game aggregates for the user, overall and since recentSince
+func (r *GamePlayRepo) Stats(ctx context.Context, userID string, recentSince time.Time) ([]GamePlayStats, error) {
+	const q = `
+SELECT game_id,
+       COUNT(*),
+       COALESCE(AVG(score), 0)::FLOAT8,
+       COALESCE(MAX(score), 0),
+       COUNT(*) FILTER (WHERE ended_at >= $2),
+       COALESCE(AVG(score) FILTER (WHERE ended_at >= $2), 0)::FLOAT8,
+       COALESCE(MAX(score) FILTER (WHERE ended_at >= $2), 0)
+FROM game_plays
+WHERE user_id = $1
+GROUP BY game_id
+ORDER BY game_id ASC`
+	rows, err := r.db.Query(ctx, q, userID, recentSince)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []GamePlayStats{}
+	for rows.Next() {
+		var s GamePlayStats
+		if err := rows.Scan(&s.GameID, &s.Plays, &s.Average, &s.Best,
+			&s.Last7Day.Plays, &s.Last7Day.Average, &s.Last7Day.Best); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// Totals returns the user's play count, best score and multiplayer wins per game
+func (r *GamePlayRepo) Totals(ctx context.Context, userID string) (plays, best, wins map[string]int, err error) {
+	const q = `
+SELECT game_id, COUNT(*), COALESCE(MAX(score), 0),
+       COUNT(*) FILTER (WHERE mode = 'multiplayer' AND outcome = 'win')
+FROM game_plays
+WHERE user_id = $1
+GROUP BY game_id`
+	rows, err := r.db.Query(ctx, q, userID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer rows.Close()
+
+	plays, best, wins = map[string]int{}, map[string]int{}, map[string]int{}
+	for rows.Next() {
+		var id string
+		var n, b, w int
+		if err := rows.Scan(&id, &n, &b, &w); err != nil {
+			return nil, nil, nil, err
+		}
+		plays[id], best[id], wins[id] = n, b, w
+	}
+	return plays, best, wins, rows.Err()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -52,60 +53,39 @@ func (r *GameRepo) GetAllByUserID(ctx context.Context, userID string) ([]Game, e
 	return games, rows.Err()
 }
 
+// UpsertHighScore raises the stored high score to highScore if it is higher
 func (r *GameRepo) UpsertHighScore(ctx context.Context, userID, title string, highScore int) (*Game, error) {
-	// First, try to get existing game
-	existing, err := r.GetByUserIDAndTitle(ctx, userID, title)
-
-	var currentHighScore int
-	if err == nil && existing != nil {
-		// Extract current high score from attrs
-		if score, ok := existing.Attrs["high_score"].(float64); ok {
-			currentHighScore = int(score)
-		} else if score, ok := existing.Attrs["high_score"].(int); ok {
-			currentHighScore = score
-		}
-
-		// Only update if new score is higher
-		if highScore <= currentHighScore {
-			return existing, nil
-		}
-	}
-
-	// Prepare attrs with high_score
-	attrs := make(map[string]any)
-	if existing != nil {
-		// Preserve existing attrs
-		for k, v := range existing.Attrs {
-			attrs[k] = v
-		}
-	}
-	attrs["high_score"] = highScore
+	return r.upsertHighScore(ctx, r.db, userID, title, highScore)
+}
 
-	attrsJSON, err := json.Marshal(attrs)
-	if err != nil {
-		return nil, err
-	}
+// UpsertHighScoreTx is UpsertHighScore inside a caller-managed transaction
+func (r *GameRepo) UpsertHighScoreTx(ctx context.Context, tx pgx.Tx, userID, title string, highScore int) (*Game, error) {
+	return r.upsertHighScore(ctx, tx, userID, title, highScore)
+}
 
-	// Upsert: insert or update
-	const q = `
-INSERT INTO games (user_id, title, status, attrs)
-VALUES ($1, $2, 'active', $3)
+func (r *GameRepo) upsertHighScore(ctx context.Context, q querier, userID, title string, highScore int) (*Game, error) {
+	// Insert or update in one statement; GREATEST keeps the existing score if it is higher
+	// and other attrs are preserved by only setting the high_score key.
+	// The high_score column mirrors attrs so leaderboards can use an index.
+	const sql = `
+INSERT INTO games (user_id, title, status, attrs, high_score)
+VALUES ($1, $2, 'active', jsonb_build_object('high_score', $3::INT), $3::INT)
 ON CONFLICT (user_id, title) DO UPDATE
-  SET attrs = EXCLUDED.attrs,
+  SET attrs = jsonb_set(games.attrs, '{high_score}',
+        to_jsonb(GREATEST(COALESCE((games.attrs->>'high_score')::INT, 0), $3::INT))),
+      high_score = GREATEST(games.high_score, $3::INT),
       updated_at = now()
 RETURNING id, user_id, title, status, attrs, created_at, updated_at`
 
 	var g Game
-	var attrsJSONResult []byte
-	if err := r.db.QueryRow(ctx, q, userID, title, attrsJSON).
-		Scan(&g.ID, &g.UserID, &g.Title, &g.Status, &attrsJSONResult, &g.CreatedAt, &g.UpdatedAt); err != nil {
+	var attrsJSON []byte
+	if err := q.QueryRow(ctx, sql, userID, title, highScore).
+		Scan(&g.ID, &g.UserID, &g.Title, &g.Status, &attrsJSON, &g.CreatedAt, &g.UpdatedAt); err != nil {
 		return nil, err
 	}
-
-	if err := json.Unmarshal(attrsJSONResult, &g.Attrs); err != nil {
-		g.Attrs = attrs
+	if err := json.Unmarshal(attrsJSON, &g.Attrs); err != nil {
+		g.Attrs = map[string]any{"high_score": highScore}
 	}
-
 	return &g, nil
 }
 
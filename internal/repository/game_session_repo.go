@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	GameSessionOpen     = "open"
+	GameSessionFinished = "finished"
+	GameSessionRejected = "rejected" // Finished with an implausible score, no rewards given
+)
+
+// ErrSessionNotOpen is returned when finishing a session that is unknown,
+// belongs to someone else, was already finished or has expired
+var ErrSessionNotOpen = errors.New("game session not open")
+
+type GameSessionRepo struct{ db *pgxpool.Pool }
+
+func NewGameSessionRepo(db *pgxpool.Pool) *GameSessionRepo { return &GameSessionRepo{db: db} }
+
+const gameSessionColumns = `id, user_id, game_id, status, score, started_at, expires_at, finished_at`
+
+func scanGameSession(row pgx.Row) (*GameSession, error) {
+	var s GameSession
+	if err := row.Scan(&s.ID, &s.UserID, &s.GameID, &s.Status, &s.Score, &s.StartedAt, &s.ExpiresAt, &s.FinishedAt); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// CreateTx opens a new session for the user that expires after ttl
+func (r *GameSessionRepo) CreateTx(ctx context.Context, tx pgx.Tx, userID, gameID string, ttl time.Duration) (*GameSession, error) {
+	const q = `
+INSERT INTO game_sessions (user_id, game_id, expires_at)
+VALUES ($1, $2, now() + $3::INTERVAL)
+RETURNING ` + gameSessionColumns
+	return scanGameSession(tx.QueryRow(ctx, q, userID, gameID, ttl))
+}
+
+// LockOpenTx selects an open, unexpired session of the user FOR UPDATE.
+// Fails with ErrSessionNotOpen otherwise.
+func (r *GameSessionRepo) LockOpenTx(ctx context.Context, tx pgx.Tx, id, userID string) (*GameSession, error) {
+	const q = `
+SELECT ` + gameSessionColumns + ` FROM game_sessions
+WHERE id = $1 AND user_id = $2 AND status = 'open' AND expires_at > now()
+FOR UPDATE`
+	s, err := scanGameSession(tx.QueryRow(ctx, q, id, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSessionNotOpen
+	}
+	return s, err
+}
+
+// FinishTx records the score and closes a session locked with LockOpenTx.
+// status is GameSessionFinished or GameSessionRejected.
+func (r *GameSessionRepo) FinishTx(ctx context.Context, tx pgx.Tx, id, status string, score int) (*GameSession, error) {
+	const q = `
+UPDATE game_sessions SET status = $2, score = $3, finished_at = now()
+WHERE id = $1 AND status = 'open'
+RETURNING ` + gameSessionColumns
+	s, err := scanGameSession(tx.QueryRow(ctx, q, id, status, score))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSessionNotOpen
+	}
+	return s, err
+}
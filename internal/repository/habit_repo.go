@@ -2,9 +2,12 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -15,9 +18,13 @@ func NewHabitRepo(db *pgxpool.Pool) *HabitRepo {
 }
 
 func (r *HabitRepo) GetByID(ctx context.Context, id string) (*Habit, error) {
-	const q = `SELECT id, user_id, title, done, icons, cadence, created_at, updated_at FROM habits WHERE id = $1`
+	return r.getByID(ctx, r.db, id)
+}
+
+func (r *HabitRepo) getByID(ctx context.Context, q querier, id string) (*Habit, error) {
+	const sql = `SELECT id, user_id, title, done, icons, cadence, created_at, updated_at FROM habits WHERE id = $1`
 	var h Habit
-	if err := r.db.QueryRow(ctx, q, id).
+	if err := q.QueryRow(ctx, sql, id).
 		Scan(&h.ID, &h.UserID, &h.Title, &h.Done, &h.Icons, &h.Cadence, &h.CreatedAt, &h.UpdatedAt); err != nil {
 		return nil, err
 	}
@@ -58,6 +65,15 @@ RETURNING id, user_id, title, done, icons, cadence, created_at, updated_at`
 }
 
 func (r *HabitRepo) Update(ctx context.Context, id string, title *string, done *bool, icons *string, cadence *string) (*Habit, error) {
+	return r.update(ctx, r.db, id, title, done, icons, cadence)
+}
+
+// UpdateTx is Update inside a caller-managed transaction
+func (r *HabitRepo) UpdateTx(ctx context.Context, tx pgx.Tx, id string, title *string, done *bool, icons *string, cadence *string) (*Habit, error) {
+	return r.update(ctx, tx, id, title, done, icons, cadence)
+}
+
+func (r *HabitRepo) update(ctx context.Context, q querier, id string, title *string, done *bool, icons *string, cadence *string) (*Habit, error) {
 	// Build dynamic UPDATE query based on provided fields
 	updates := []string{}
 	args := []interface{}{}
@@ -86,7 +102,7 @@ func (r *HabitRepo) Update(ctx context.Context, id string, title *string, done *
 
 	if len(updates) == 0 {
 		// No fields to update, just return the existing habit
-		return r.GetByID(ctx, id)
+		return r.getByID(ctx, q, id)
 	}
 
 	// Add updated_at
@@ -96,12 +112,12 @@ func (r *HabitRepo) Update(ctx context.Context, id string, title *string, done *
 	args = append(args, id)
 
 	// Build the query
-	q := fmt.Sprintf(`UPDATE habits SET %s WHERE id = $%d
+	sql := fmt.Sprintf(`UPDATE habits SET %s WHERE id = $%d
 		RETURNING id, user_id, title, done, icons, cadence, created_at, updated_at`,
 		strings.Join(updates, ", "), argPos)
 
 	var h Habit
-	if err := r.db.QueryRow(ctx, q, args...).
+	if err := q.QueryRow(ctx, sql, args...).
 		Scan(&h.ID, &h.UserID, &h.Title, &h.Done, &h.Icons, &h.Cadence, &h.CreatedAt, &h.UpdatedAt); err != nil {
 		return nil, err
 	}
@@ -113,3 +129,74 @@ func (r *HabitRepo) Delete(ctx context.Context, id string) error {
 	return err
 }
 
+
+// SetDoneTx flips the user's habit to done inside tx. It reports false without
+// changing anything if the habit already had that state, so concurrent requests
+// making the same transition can't both see it as theirs.
+func (r *HabitRepo) SetDoneTx(ctx context.Context, tx pgx.Tx, id, userID string, done bool) (bool, error) {
+	const q = `
+UPDATE habits SET done = $2, updated_at = NOW()
+WHERE id = $1 AND user_id = $3 AND done IS DISTINCT FROM $2
+RETURNING id`
+	var updatedID string
+	err := tx.QueryRow(ctx, q, id, done, userID).Scan(&updatedID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// AddCompletionTx logs inside tx that the habit was completed at now (bucketed by UTC day)
+func (r *HabitRepo) AddCompletionTx(ctx context.Context, tx pgx.Tx, userID, habitID string, now time.Time) error {
+	const q = `INSERT INTO habit_completions (user_id, habit_id, completed_on, completed_at) VALUES ($1, $2, $3, $4)`
+	_, err := tx.Exec(ctx, q, userID, habitID, now.UTC().Format("2006-01-02"), now)
+	return err
+}
+
+// RemoveLatestCompletionTx undoes inside tx the most recent completion of the
+// habit and returns when it was made, or nil if there was none
+func (r *HabitRepo) RemoveLatestCompletionTx(ctx context.Context, tx pgx.Tx, userID, habitID string) (*time.Time, error) {
+	const q = `
+DELETE FROM habit_completions WHERE id = (
+  SELECT id FROM habit_completions WHERE user_id = $1 AND habit_id = $2
+  ORDER BY completed_at DESC LIMIT 1
+)
+RETURNING completed_at`
+	var completedAt time.Time
+	err := tx.QueryRow(ctx, q, userID, habitID).Scan(&completedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &completedAt, nil
+}
+
+// CompletionCount returns how many habit completions the user has logged
+func (r *HabitRepo) CompletionCount(ctx context.Context, userID string) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM habit_completions WHERE user_id = $1`, userID).Scan(&n)
+	return n, err
+}
+
+// CompletionDays returns the distinct UTC days with at least one completion, newest first
+func (r *HabitRepo) CompletionDays(ctx context.Context, userID string, limit int) ([]time.Time, error) {
+	const q = `SELECT DISTINCT completed_on FROM habit_completions WHERE user_id = $1
+	           ORDER BY completed_on DESC LIMIT $2`
+	rows, err := r.db.Query(ctx, q, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []time.Time
+	for rows.Next() {
+		var d time.Time
+		if err := rows.Scan(&d); err != nil {
+			return nil, err
+		}
+		days = append(days, d)
+	}
+	return days, rows.Err()
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	// ErrInsufficientCoins is returned when a purchase costs more than the wallet holds
+	ErrInsufficientCoins = errors.New("insufficient coins")
+	// ErrItemNotOwned is returned when using an item the user does not have
+	ErrItemNotOwned = errors.New("item not owned")
+)
+
+// InventoryRepo manages each user's coin wallet and owned items
+type InventoryRepo struct{ db *pgxpool.Pool }
+
+func NewInventoryRepo(db *pgxpool.Pool) *InventoryRepo { return &InventoryRepo{db: db} }
+
+// GetCoins returns the user's coin balance (0 if they never earned any)
+func (r *InventoryRepo) GetCoins(ctx context.Context, userID string) (int, error) {
+	var coins int
+	err := r.db.QueryRow(ctx, `SELECT coins FROM wallets WHERE user_id = $1`, userID).Scan(&coins)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	return coins, err
+}
+
+// AddCoins credits (or, for a negative amount, debits) the user's wallet and
+// returns the new balance. Debits never take the balance below zero; use
+// SpendCoinsTx to take back coins that must be paid in full.
+func (r *InventoryRepo) AddCoins(ctx context.Context, userID string, amount int) (int, error) {
+	return r.addCoins(ctx, r.db, userID, amount)
+}
+
+// AddCoinsTx is AddCoins inside a caller-managed transaction
+func (r *InventoryRepo) AddCoinsTx(ctx context.Context, tx pgx.Tx, userID string, amount int) (int, error) {
+	return r.addCoins(ctx, tx, userID, amount)
+}
+
+func (r *InventoryRepo) addCoins(ctx context.Context, q querier, userID string, amount int) (int, error) {
+	const sql = `
+INSERT INTO wallets (user_id, coins)
+VALUES ($1, GREATEST(0, $2))
+ON CONFLICT (user_id) DO UPDATE
+  SET coins = GREATEST(0, wallets.coins + $2),
+      updated_at = now()
+RETURNING coins`
+	var coins int
+	err := q.QueryRow(ctx, sql, userID, amount).Scan(&coins)
+	return coins, err
+}
+
+// SpendCoinsTx deducts amount from the wallet inside tx and returns the new balance.
+// Fails with ErrInsufficientCoins without changing anything if the balance is too low.
+func (r *InventoryRepo) SpendCoinsTx(ctx context.Context, tx pgx.Tx, userID string, amount int) (int, error) {
+	const sql = `
+UPDATE wallets SET coins = coins - $2, updated_at = now()
+WHERE user_id = $1 AND coins >= $2
+RETURNING coins`
+	var coins int
+	err := tx.QueryRow(ctx, sql, userID, amount).Scan(&coins)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrInsufficientCoins
+	}
+	return coins, err
+}
+
+// List returns the items the user owns, skipping used-up entries
+func (r *InventoryRepo) List(ctx context.Context, userID string) ([]InventoryItem, error) {
+	const q = `SELECT item_id, quantity, updated_at FROM inventory_items
+	           WHERE user_id = $1 AND quantity > 0 ORDER BY item_id ASC`
+	rows, err := r.db.Query(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []InventoryItem{}
+	for rows.Next() {
+		var it InventoryItem
+		if err := rows.Scan(&it.ItemID, &it.Quantity, &it.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
+
+// GetQuantityTx returns how many of an item the user owns
+func (r *InventoryRepo) GetQuantityTx(ctx context.Context, tx pgx.Tx, userID, itemID string) (int, error) {
+	var qty int
+	err := tx.QueryRow(ctx, `SELECT quantity FROM inventory_items WHERE user_id = $1 AND item_id = $2`, userID, itemID).Scan(&qty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	return qty, err
+}
+
+// AddItemTx gives the user quantity more of an item and returns the new quantity
+func (r *InventoryRepo) AddItemTx(ctx context.Context, tx pgx.Tx, userID, itemID string, quantity int) (int, error) {
+	const sql = `
+INSERT INTO inventory_items (user_id, item_id, quantity)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, item_id) DO UPDATE
+  SET quantity = inventory_items.quantity + EXCLUDED.quantity,
+      updated_at = now()
+RETURNING quantity`
+	var qty int
+	err := tx.QueryRow(ctx, sql, userID, itemID, quantity).Scan(&qty)
+	return qty, err
+}
+
+// ConsumeItemTx removes one of an item and returns the remaining quantity.
+// Fails with ErrItemNotOwned if the user has none.
+func (r *InventoryRepo) ConsumeItemTx(ctx context.Context, tx pgx.Tx, userID, itemID string) (int, error) {
+	const sql = `
+UPDATE inventory_items SET quantity = quantity - 1, updated_at = now()
+WHERE user_id = $1 AND item_id = $2 AND quantity > 0
+RETURNING quantity`
+	var qty int
+	err := tx.QueryRow(ctx, sql, userID, itemID).Scan(&qty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrItemNotOwned
+	}
+	return qty, err
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Leaderboard windows
+const (
+	LeaderboardAllTime = "all"
+	LeaderboardWeekly  = "weekly"
+	LeaderboardDaily   = "daily"
+)
+
+// LeaderboardQuery selects which scores are ranked.
+// All-time boards use games.high_score (by Title); windowed boards use the best
+// play per user in game_plays (by GameID) with ended_at in [Since, Until).
+type LeaderboardQuery struct {
+	Title   string
+	GameID  string
+	Window  string
+	Since   time.Time
+	Until   time.Time
+	UserIDs []string // Restrict to these users; nil means everyone
+}
+
+type LeaderboardRepo struct{ db *pgxpool.Pool }
+
+func NewLeaderboardRepo(db *pgxpool.Pool) *LeaderboardRepo { return &LeaderboardRepo{db: db} }
+
+// rankedCTE ranks every user's best score for the query. Ties share a rank
+// and pos orders users uniquely so neighbours can be sliced around a position.
+// Parameters: $1 title or game id, $2 user id scope, and $3/$4 the window bounds.
+func (q LeaderboardQuery) rankedCTE() (string, []any) {
+	var scores string
+	args := []any{q.Title, q.UserIDs}
+	if q.Window == LeaderboardAllTime {
+		scores = `
+  SELECT user_id, high_score AS score
+  FROM games
+  WHERE title = $1 AND high_score > 0
+    AND ($2::UUID[] IS NULL OR user_id = ANY($2::UUID[]))`
+	} else {
+		scores = `
+  SELECT user_id, MAX(score) AS score
+  FROM game_plays
+  WHERE game_id = $1 AND ended_at >= $3 AND ended_at < $4 AND score > 0
+    AND ($2::UUID[] IS NULL OR user_id = ANY($2::UUID[]))
+  GROUP BY user_id`
+		args = []any{q.GameID, q.UserIDs, q.Since, q.Until}
+	}
+	return `
+WITH scores AS (` + scores + `
+), ranked AS (
+  SELECT s.user_id, u.display_name, s.score,
+         RANK() OVER (ORDER BY s.score DESC) AS rank,
+         ROW_NUMBER() OVER (ORDER BY s.score DESC, s.user_id ASC) AS pos
+  FROM scores s JOIN users u ON u.id = s.user_id
+)`, args
+}
+
+// Top returns the first limit entries of the leaderboard and the number of ranked users
+func (r *LeaderboardRepo) Top(ctx context.Context, q LeaderboardQuery, limit int) ([]LeaderboardEntry, int, error) {
+	cte, args := q.rankedCTE()
+	n := len(args)
+	sql := cte + `
+SELECT user_id, display_name, score, rank, (SELECT COUNT(*) FROM ranked)
+FROM ranked
+ORDER BY pos ASC
+LIMIT $` + strconv.Itoa(n+1)
+
+	rows, err := r.db.Query(ctx, sql, append(args, limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	out := []LeaderboardEntry{}
+	total := 0
+	for rows.Next() {
+		var e LeaderboardEntry
+		if err := rows.Scan(&e.UserID, &e.DisplayName, &e.Score, &e.Rank, &total); err != nil {
+			return nil, 0, err
+		}
+		out = append(out, e)
+	}
+	return out, total, rows.Err()
+}
+
+// Around returns userID's entry with up to n neighbours on each side.
+// The result is empty if the user has no score on the leaderboard.
+func (r *LeaderboardRepo) Around(ctx context.Context, q LeaderboardQuery, userID string, n int) ([]LeaderboardEntry, error) {
+	cte, args := q.rankedCTE()
+	i := len(args)
+	sql := cte + `, me AS (
+  SELECT pos FROM ranked WHERE user_id = $` + strconv.Itoa(i+1) + `
+)
+SELECT r.user_id, r.display_name, r.score, r.rank
+FROM ranked r, me
+WHERE r.pos BETWEEN me.pos - $` + strconv.Itoa(i+2) + ` AND me.pos + $` + strconv.Itoa(i+2) + `
+ORDER BY r.pos ASC`
+
+	rows, err := r.db.Query(ctx, sql, append(args, userID, n)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []LeaderboardEntry{}
+	for rows.Next() {
+		var e LeaderboardEntry
+		if err := rows.Scan(&e.UserID, &e.DisplayName, &e.Score, &e.Rank); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
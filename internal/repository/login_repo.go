@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"fsd-backend/internal/login"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	// ErrNoLogin is returned when claiming a day the user wasn't recorded as active
+	ErrNoLogin = errors.New("no login recorded for day")
+	// ErrLoginClaimed is returned when the day's login reward was already claimed
+	ErrLoginClaimed = errors.New("login reward already claimed")
+)
+
+// LoginRepo records the days each user was active, in their time zone, and
+// the streak of consecutive days ending on each of them
+type LoginRepo struct{ db *pgxpool.Pool }
+
+func NewLoginRepo(db *pgxpool.Pool) *LoginRepo { return &LoginRepo{db: db} }
+
+// Location returns the user's time zone
+func (r *LoginRepo) Location(ctx context.Context, userID string) (*time.Location, error) {
+	var name string
+	if err := r.db.QueryRow(ctx, `SELECT timezone FROM users WHERE id = $1`, userID).Scan(&name); err != nil {
+		return nil, err
+	}
+	return login.Location(name), nil
+}
+
+// RecordLogin records that the user is active today in their time zone and
+// returns when their next day starts. Only the first call per day writes;
+// the streak continues if the previous day was recorded and restarts otherwise.
+func (r *LoginRepo) RecordLogin(ctx context.Context, userID string) (time.Time, error) {
+	loc, err := r.Location(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	day, next := login.Day(time.Now(), loc)
+	const q = `
+INSERT INTO daily_logins (user_id, day, streak)
+SELECT $1::UUID, $2::DATE,
+       COALESCE((SELECT streak FROM daily_logins WHERE user_id = $1::UUID AND day = $2::DATE - 1), 0) + 1
+ON CONFLICT (user_id, day) DO NOTHING`
+	if _, err := r.db.Exec(ctx, q, userID, day); err != nil {
+		return time.Time{}, err
+	}
+	return next, nil
+}
+
+// Range returns the user's login days in [from, to], oldest first
+func (r *LoginRepo) Range(ctx context.Context, userID string, from, to time.Time) ([]DailyLogin, error) {
+	const q = `SELECT day, streak, first_seen_at, claimed_at FROM daily_logins
+WHERE user_id = $1 AND day BETWEEN $2::DATE AND $3::DATE
+ORDER BY day ASC`
+	rows, err := r.db.Query(ctx, q, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []DailyLogin{}
+	for rows.Next() {
+		var l DailyLogin
+		if err := rows.Scan(&l.Day, &l.Streak, &l.FirstSeenAt, &l.ClaimedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
+
+// ClaimTx marks the day's login as claimed inside tx and returns it.
+// Only one claim can succeed; the others fail with ErrLoginClaimed.
+func (r *LoginRepo) ClaimTx(ctx context.Context, tx pgx.Tx, userID string, day time.Time) (*DailyLogin, error) {
+	const q = `
+UPDATE daily_logins SET claimed_at = now()
+WHERE user_id = $1 AND day = $2::DATE AND claimed_at IS NULL
+RETURNING day, streak, first_seen_at, claimed_at`
+	var l DailyLogin
+	err := tx.QueryRow(ctx, q, userID, day).Scan(&l.Day, &l.Streak, &l.FirstSeenAt, &l.ClaimedAt)
+	if !errors.Is(err, pgx.ErrNoRows) {
+		if err != nil {
+			return nil, err
+		}
+		return &l, nil
+	}
+
+	var exists bool
+	const sel = `SELECT EXISTS (SELECT 1 FROM daily_logins WHERE user_id = $1 AND day = $2::DATE)`
+	if err := tx.QueryRow(ctx, sel, userID, day).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrLoginClaimed
+	}
+	return nil, ErrNoLogin
+}
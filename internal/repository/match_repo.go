@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MatchRepo stores finished multiplayer matches and each participant's result
+type MatchRepo struct{ db *pgxpool.Pool }
+
+func NewMatchRepo(db *pgxpool.Pool) *MatchRepo { return &MatchRepo{db: db} }
+
+// CreateTx stores m and its players inside tx; ID is filled in on m
+func (r *MatchRepo) CreateTx(ctx context.Context, tx pgx.Tx, m *Match) error {
+	const q = `
+INSERT INTO matches (game_id, room_id, private, rounds, winner_id, started_at, ended_at, duration_ms)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id`
+	err := tx.QueryRow(ctx, q, m.GameID, m.RoomID, m.Private, m.Rounds, m.WinnerID,
+		m.StartedAt, m.EndedAt, m.DurationMs).Scan(&m.ID)
+	if err != nil {
+		return err
+	}
+
+	const ins = `
+INSERT INTO match_players (match_id, place, user_id, score, outcome, forfeit, ended_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	for _, p := range m.Players {
+		if _, err := tx.Exec(ctx, ins, m.ID, p.Place, p.UserID, p.Score, p.Outcome, p.Forfeit, p.EndedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,9 +3,14 @@ package repository
 import (
 	"context"
 	"encoding/json"
-	"math"
+	"errors"
+	"sort"
 	"time"
 
+	"fsd-backend/internal/db"
+	"fsd-backend/internal/pet"
+
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -38,7 +43,7 @@ RETURNING id, user_id, name, species, attrs, created_at, updated_at`
 
 // GetByUserID gets the first pet for a user (assuming one pet per user)
 func (r *PetRepo) GetByUserID(ctx context.Context, userID string) (*Pet, error) {
-	const q = `SELECT id, user_id, name, species, attrs, created_at, updated_at FROM pets WHERE user_id = $1 LIMIT 1`
+	const q = `SELECT id, user_id, name, species, attrs, created_at, updated_at FROM pets WHERE user_id = $1 ORDER BY created_at ASC LIMIT 1`
 	var p Pet
 	var attrsJSON []byte
 	if err := r.db.QueryRow(ctx, q, userID).
@@ -51,122 +56,284 @@ func (r *PetRepo) GetByUserID(ctx context.Context, userID string) (*Pet, error)
 	return &p, nil
 }
 
-// GetMood gets the pet's mood with passive drain calculation
-// Mood drains -1 every 25 minutes since last update
-func (r *PetRepo) GetMood(ctx context.Context, userID string) (int, error) {
-	pet, err := r.GetByUserID(ctx, userID)
+// GetState returns the pet's stats with passive decay applied up to now.
+// Decay is persisted so the next read continues from the new values.
+func (r *PetRepo) GetState(ctx context.Context, petID string) (*Pet, *pet.State, error) {
+	return r.UpdateState(ctx, petID, nil)
+}
+
+// UpdateState locks the pet, applies passive decay, lets fn change the state
+// and writes it back in a single transaction
+func (r *PetRepo) UpdateState(ctx context.Context, petID string, fn func(*pet.State) error) (*Pet, *pet.State, error) {
+	var p *Pet
+	var state *pet.State
+	err := db.WithTxnRetry(ctx, r.db, func(tx pgx.Tx) error {
+		var err error
+		p, state, err = r.UpdateStateTx(ctx, tx, petID, fn)
+		return err
+	})
 	if err != nil {
-		// If pet doesn't exist, return default mood
-		return 50, nil
-	}
-
-	// Get current mood from attrs
-	var currentMood int
-	if mood, ok := pet.Attrs["mood"].(float64); ok {
-		currentMood = int(mood)
-	} else if mood, ok := pet.Attrs["mood"].(int); ok {
-		currentMood = mood
-	} else {
-		// Default mood is 50
-		currentMood = 50
-	}
-
-	// Get last mood update time
-	var lastUpdate time.Time
-	if lastUpdateStr, ok := pet.Attrs["mood_last_updated"].(string); ok {
-		if parsed, err := time.Parse(time.RFC3339, lastUpdateStr); err == nil {
-			lastUpdate = parsed
-		} else {
-			lastUpdate = pet.UpdatedAt
+		return nil, nil, err
+	}
+	return p, state, nil
+}
+
+// UpdateStateTx is UpdateState inside a caller-managed transaction, so other
+// writes (e.g. spending energy) commit or roll back together with the pet
+func (r *PetRepo) UpdateStateTx(ctx context.Context, tx pgx.Tx, petID string, fn func(*pet.State) error) (*Pet, *pet.State, error) {
+	p, err := scanPet(tx.QueryRow(ctx, `SELECT `+petColumns+` FROM pets WHERE id = $1 FOR UPDATE`, petID))
+	if err != nil {
+		return nil, nil, err
+	}
+	state, err := r.mutateState(ctx, tx, p, time.Now(), fn)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, state, nil
+}
+
+// UpdateStateForUser is UpdateState for the user's pet.
+// If the user has no pet yet a default one is created first.
+func (r *PetRepo) UpdateStateForUser(ctx context.Context, userID string, fn func(*pet.State) error) (*Pet, *pet.State, error) {
+	var p *Pet
+	var state *pet.State
+	err := db.WithTxnRetry(ctx, r.db, func(tx pgx.Tx) error {
+		var err error
+		p, err = r.LockByUserIDTx(ctx, tx, userID)
+		if err != nil {
+			return err
 		}
-	} else {
-		lastUpdate = pet.UpdatedAt
+		state, err = r.mutateState(ctx, tx, p, time.Now(), fn)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, state, nil
+}
+
+// PeekStateForUser returns the user's pet stats with passive decay applied up to
+// now, without locking the pet or writing anything. It returns nil if the user
+// has no pet yet.
+func (r *PetRepo) PeekStateForUser(ctx context.Context, userID string) (*pet.State, error) {
+	p, err := r.GetByUserID(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sp := pet.LookupSpecies(p.Species)
+	state := pet.FromAttrs(p.Attrs, sp, p.UpdatedAt)
+	state.ApplyDecay(sp.Decay, time.Now())
+	return &state, nil
+}
+
+// LockByUserIDTx selects the user's pet FOR UPDATE, creating a default pet if none exists
+func (r *PetRepo) LockByUserIDTx(ctx context.Context, tx pgx.Tx, userID string) (*Pet, error) {
+	const sel = `SELECT ` + petColumns + ` FROM pets WHERE user_id = $1 ORDER BY created_at ASC LIMIT 1 FOR UPDATE`
+	p, err := scanPet(tx.QueryRow(ctx, sel, userID))
+	if err == nil || !errors.Is(err, pgx.ErrNoRows) {
+		return p, err
 	}
 
-	// Calculate passive drain: -1 every 25 minutes
-	minutesSinceUpdate := time.Since(lastUpdate).Minutes()
-	drainAmount := int(math.Floor(minutesSinceUpdate / 25.0))
+	const ins = `
+INSERT INTO pets (user_id, name, species, attrs)
+VALUES ($1, 'My Pet', 'default', '{}'::JSONB)
+RETURNING ` + petColumns
+	return scanPet(tx.QueryRow(ctx, ins, userID))
+}
 
-	// Apply drain (mood can't go below 0)
-	newMood := currentMood - drainAmount
-	if newMood < 0 {
-		newMood = 0
+// mutateState decays the pet's stats up to now, applies fn, evolves the pet if
+// its species allows it and persists the result if anything changed. p.Attrs and p.UpdatedAt are updated in place.
+func (r *PetRepo) mutateState(ctx context.Context, q querier, p *Pet, now time.Time, fn func(*pet.State) error) (*pet.State, error) {
+	sp := pet.LookupSpecies(p.Species)
+	state := pet.FromAttrs(p.Attrs, sp, p.UpdatedAt)
+	stage := state.Stage
+	changed := state.ApplyDecay(sp.Decay, now)
+	if fn != nil {
+		if err := fn(&state); err != nil {
+			return nil, err
+		}
+		changed = true
+	}
+	state.Events = sp.Evolve(&state, now)
+	if state.Stage != stage {
+		changed = true
+	}
+	if !changed {
+		return &state, nil
 	}
 
-	// If mood changed, update it in the database
-	if drainAmount > 0 {
-		if err := r.UpdateMood(ctx, userID, newMood); err != nil {
-			// If update fails, return the calculated mood anyway
-			return newMood, nil
+	for i := range state.Events {
+		if err := r.insertEvent(ctx, q, p.ID, &state.Events[i]); err != nil {
+			return nil, err
 		}
 	}
 
-	return newMood, nil
+	state.WriteAttrs(p.Attrs)
+	attrsJSON, err := json.Marshal(p.Attrs)
+	if err != nil {
+		return nil, err
+	}
+	const q2 = `UPDATE pets SET attrs = $2, updated_at = now() WHERE id = $1 RETURNING updated_at`
+	if err := q.QueryRow(ctx, q2, p.ID, attrsJSON).Scan(&p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &state, nil
 }
 
-// UpdateMood updates the pet's mood and records the update time
-// If pet doesn't exist, creates a default pet with the specified mood
-func (r *PetRepo) UpdateMood(ctx context.Context, userID string, mood int) error {
-	// Ensure mood is within bounds
-	if mood < 0 {
-		mood = 0
-	} else if mood > 100 {
-		mood = 100
+// UpdateLoadoutTx locks the pet, lets fn change its equipped cosmetics and writes them back
+func (r *PetRepo) UpdateLoadoutTx(ctx context.Context, tx pgx.Tx, petID string, fn func(pet.Loadout) error) (*Pet, error) {
+	p, err := scanPet(tx.QueryRow(ctx, `SELECT `+petColumns+` FROM pets WHERE id = $1 FOR UPDATE`, petID))
+	if err != nil {
+		return nil, err
 	}
+	loadout := pet.LoadoutFromAttrs(p.Attrs)
+	if err := fn(loadout); err != nil {
+		return nil, err
+	}
+	loadout.WriteAttrs(p.Attrs)
 
-	// Get current pet to preserve other attrs
-	pet, err := r.GetByUserID(ctx, userID)
+	attrsJSON, err := json.Marshal(p.Attrs)
 	if err != nil {
-		// If pet doesn't exist, create a default pet
-		attrs := make(map[string]any)
-		attrs["mood"] = mood
-		attrs["mood_last_updated"] = time.Now().Format(time.RFC3339)
-
-		// Try to create pet
-		_, createErr := r.Create(ctx, userID, "My Pet", "default", attrs)
-		if createErr != nil {
-			// If creation failed (e.g., pet was created concurrently), try to get it again
-			pet, err = r.GetByUserID(ctx, userID)
-			if err != nil {
-				// Still can't get pet, return original create error
-				return createErr
-			}
-			// Pet was created by another request, continue with update below
-		} else {
-			// Pet created successfully, we're done
-			return nil
-		}
+		return nil, err
+	}
+	const q = `UPDATE pets SET attrs = $2, updated_at = now() WHERE id = $1 RETURNING updated_at`
+	if err := tx.QueryRow(ctx, q, p.ID, attrsJSON).Scan(&p.UpdatedAt); err != nil {
+		return nil, err
 	}
+	return p, nil
+}
+
+// GetAppearanceByUserID returns how the user's pet looks, or nil if they have no pet yet
+func (r *PetRepo) GetAppearanceByUserID(ctx context.Context, userID string) (*pet.Appearance, error) {
+	p, err := r.GetByUserID(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p.Appearance(), nil
+}
 
-	// Update attrs (either pet existed or was just created)
-	if pet.Attrs == nil {
-		pet.Attrs = make(map[string]any)
+// Appearance builds the payload clients use to render the pet
+func (p *Pet) Appearance() *pet.Appearance {
+	sp := pet.LookupSpecies(p.Species)
+	state := pet.FromAttrs(p.Attrs, sp, p.UpdatedAt)
+	stage := state.Stage
+	if stage == "" && len(sp.Stages) > 0 {
+		stage = sp.Stages[0].ID
+	}
+	return &pet.Appearance{
+		PetID:   p.ID,
+		Name:    p.Name,
+		Species: p.Species,
+		Stage:   stage,
+		Level:   state.Level,
+		Loadout: pet.LoadoutFromAttrs(p.Attrs),
 	}
-	pet.Attrs["mood"] = mood
-	pet.Attrs["mood_last_updated"] = time.Now().Format(time.RFC3339)
+}
 
-	// Update in database
-	attrsJSON, err := json.Marshal(pet.Attrs)
+// GetMood gets the pet's mood (happiness) with passive drain applied
+func (r *PetRepo) GetMood(ctx context.Context, userID string) (int, error) {
+	p, err := r.GetByUserID(ctx, userID)
 	if err != nil {
-		return err
+		// If pet doesn't exist, return default mood
+		return pet.DefaultStats[pet.StatHappiness], nil
 	}
 
-	const q = `UPDATE pets SET attrs = $2, updated_at = now() WHERE user_id = $1`
-	_, err = r.db.Exec(ctx, q, userID, attrsJSON)
+	_, state, err := r.GetState(ctx, p.ID)
+	if err != nil {
+		return 0, err
+	}
+	return state.Happiness, nil
+}
+
+// UpdateMood sets the pet's mood and records the update time
+// If pet doesn't exist, creates a default pet with the specified mood
+func (r *PetRepo) UpdateMood(ctx context.Context, userID string, mood int) error {
+	_, _, err := r.UpdateStateForUser(ctx, userID, func(s *pet.State) error {
+		s.Add(pet.StatHappiness, mood-s.Happiness, time.Now())
+		return nil
+	})
 	return err
 }
 
 // AddMood adds to the pet's mood (for games)
-// This function gets the current mood (with passive drain applied), adds the amount, and updates
+// Passive drain is applied first, then the amount is added atomically
 func (r *PetRepo) AddMood(ctx context.Context, userID string, amount int) error {
-	// Get current mood (this will apply passive drain if needed)
-	currentMood, err := r.GetMood(ctx, userID)
+	_, _, err := r.UpdateStateForUser(ctx, userID, func(s *pet.State) error {
+		s.Add(pet.StatHappiness, amount, time.Now())
+		return nil
+	})
+	return err
+}
+
+// AddXP grants experience to the user's pet
+func (r *PetRepo) AddXP(ctx context.Context, userID string, amount int) error {
+	_, _, err := r.UpdateStateForUser(ctx, userID, func(s *pet.State) error {
+		s.AddXP(amount)
+		return nil
+	})
+	return err
+}
+
+func (r *PetRepo) insertEvent(ctx context.Context, q querier, petID string, e *pet.Event) error {
+	payload, err := json.Marshal(e)
 	if err != nil {
 		return err
 	}
+	const ins = `INSERT INTO pet_events (pet_id, type, payload, created_at) VALUES ($1, $2, $3, $4) RETURNING id`
+	return q.QueryRow(ctx, ins, petID, e.Type, payload, e.CreatedAt).Scan(&e.ID)
+}
+
+// PopEvents returns the pet's unseen events oldest first and marks them as seen
+func (r *PetRepo) PopEvents(ctx context.Context, petID string) ([]pet.Event, error) {
+	const q = `
+UPDATE pet_events SET seen_at = now()
+WHERE pet_id = $1 AND seen_at IS NULL
+RETURNING id, payload, created_at`
+	rows, err := r.db.Query(ctx, q, petID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []pet.Event{}
+	for rows.Next() {
+		var e pet.Event
+		var id string
+		var payload []byte
+		var createdAt time.Time
+		if err := rows.Scan(&id, &payload, &createdAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		e.ID = id
+		e.CreatedAt = createdAt
+		out = append(out, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
 
-	// Add the mood increase
-	newMood := currentMood + amount
-	// UpdateMood will clamp to 0-100 and update the timestamp
-	return r.UpdateMood(ctx, userID, newMood)
+const petColumns = `id, user_id, name, species, attrs, created_at, updated_at`
+
+func scanPet(row pgx.Row) (*Pet, error) {
+	var p Pet
+	var attrsJSON []byte
+	if err := row.Scan(&p.ID, &p.UserID, &p.Name, &p.Species, &attrsJSON, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(attrsJSON, &p.Attrs); err != nil || p.Attrs == nil {
+		p.Attrs = make(map[string]any)
+	}
+	return &p, nil
 }
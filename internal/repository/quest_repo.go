@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	// ErrQuestNotFound is returned when the user has no such quest
+	ErrQuestNotFound = errors.New("quest not found")
+	// ErrQuestIncomplete is returned when claiming a quest below its target
+	ErrQuestIncomplete = errors.New("quest not complete")
+	// ErrQuestClaimed is returned when the quest's reward was already claimed
+	ErrQuestClaimed = errors.New("quest already claimed")
+	// ErrQuestExpired is returned when claiming a quest after its period ended
+	ErrQuestExpired = errors.New("quest expired")
+)
+
+const userQuestColumns = `id, quest_id, period, period_start, expires_at, progress, target, reward_coins, reward_energy, claimed_at`
+
+// QuestRepo stores the quests assigned to each user per period. Targets and
+// rewards are copied from the pool on assignment so later pool edits don't
+// change quests already handed out.
+type QuestRepo struct{ db *pgxpool.Pool }
+
+func NewQuestRepo(db *pgxpool.Pool) *QuestRepo { return &QuestRepo{db: db} }
+
+// Assign records the quests for the user, ignoring any already assigned for the same period
+func (r *QuestRepo) Assign(ctx context.Context, userID string, quests []UserQuest) error {
+	const q = `
+INSERT INTO user_quests (user_id, quest_id, period, period_start, expires_at, target, reward_coins, reward_energy)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (user_id, period_start, quest_id) DO NOTHING`
+	for _, uq := range quests {
+		_, err := r.db.Exec(ctx, q, userID, uq.QuestID, uq.Period, uq.PeriodStart, uq.ExpiresAt,
+			uq.Target, uq.RewardCoins, uq.RewardEnergy)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Active returns the user's quests whose period contains now, soonest to expire first
+func (r *QuestRepo) Active(ctx context.Context, userID string, now time.Time) ([]UserQuest, error) {
+	q := `SELECT ` + userQuestColumns + ` FROM user_quests
+WHERE user_id = $1 AND period_start <= $2 AND expires_at > $2
+ORDER BY expires_at ASC, created_at ASC, quest_id ASC`
+	rows, err := r.db.Query(ctx, q, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []UserQuest{}
+	for rows.Next() {
+		uq, err := scanUserQuest(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *uq)
+	}
+	return out, rows.Err()
+}
+
+// Advance moves an unclaimed, unexpired quest's progress by delta and raises it
+// to at least atLeast, keeping it between 0 and the target
+func (r *QuestRepo) Advance(ctx context.Context, id string, delta, atLeast int) error {
+	const q = `
+UPDATE user_quests
+SET progress = LEAST(GREATEST(progress + $2, $3, 0), target)
+WHERE id = $1 AND claimed_at IS NULL AND expires_at > now()`
+	_, err := r.db.Exec(ctx, q, id, delta, atLeast)
+	return err
+}
+
+// ClaimTx marks a completed quest as claimed inside tx and returns it.
+// Only one claim can succeed; the others fail with ErrQuestClaimed. A quest
+// that can't be claimed fails with ErrQuestNotFound, ErrQuestIncomplete or ErrQuestExpired.
+func (r *QuestRepo) ClaimTx(ctx context.Context, tx pgx.Tx, userID, id string) (*UserQuest, error) {
+	q := `
+UPDATE user_quests SET claimed_at = now()
+WHERE id = $1 AND user_id = $2 AND claimed_at IS NULL AND progress >= target AND expires_at > now()
+RETURNING ` + userQuestColumns
+	uq, err := scanUserQuest(tx.QueryRow(ctx, q, id, userID))
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return uq, err
+	}
+
+	// Nothing was claimed; find out why
+	sel := `SELECT ` + userQuestColumns + ` FROM user_quests WHERE id = $1 AND user_id = $2`
+	uq, err = scanUserQuest(tx.QueryRow(ctx, sel, id, userID))
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil, ErrQuestNotFound
+	case err != nil:
+		return nil, err
+	case uq.ClaimedAt != nil:
+		return nil, ErrQuestClaimed
+	case !uq.ExpiresAt.After(time.Now()):
+		return nil, ErrQuestExpired
+	}
+	return nil, ErrQuestIncomplete
+}
+
+func scanUserQuest(row pgx.Row) (*UserQuest, error) {
+	var uq UserQuest
+	err := row.Scan(&uq.ID, &uq.QuestID, &uq.Period, &uq.PeriodStart, &uq.ExpiresAt,
+		&uq.Progress, &uq.Target, &uq.RewardCoins, &uq.RewardEnergy, &uq.ClaimedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &uq, nil
+}
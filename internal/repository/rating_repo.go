@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"fsd-backend/internal/game"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RatingRepo stores each user's Elo rating per multiplayer game
+type RatingRepo struct{ db *pgxpool.Pool }
+
+func NewRatingRepo(db *pgxpool.Pool) *RatingRepo { return &RatingRepo{db: db} }
+
+// Get returns the user's rating in the game, or game.DefaultRating if they haven't played a rated match
+func (r *RatingRepo) Get(ctx context.Context, userID, gameID string) (*Rating, error) {
+	out := &Rating{GameID: gameID, Rating: game.DefaultRating}
+	const q = `SELECT rating, matches FROM user_ratings WHERE user_id = $1 AND game_id = $2`
+	err := r.db.QueryRow(ctx, q, userID, gameID).Scan(&out.Rating, &out.Matches)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return out, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ApplyMatchTx updates both players' ratings inside tx after a scored scoreA
+// (1 win, 0.5 draw, 0 loss) against b, and returns the new ratings
+func (r *RatingRepo) ApplyMatchTx(ctx context.Context, tx pgx.Tx, gameID, aID, bID string, scoreA float64) (a, b *Rating, err error) {
+	if a, err = r.lockTx(ctx, tx, aID, gameID); err != nil {
+		return nil, nil, err
+	}
+	if b, err = r.lockTx(ctx, tx, bID, gameID); err != nil {
+		return nil, nil, err
+	}
+	a.Rating, b.Rating = game.EloUpdate(a.Rating, b.Rating, scoreA, game.EloK(a.Matches), game.EloK(b.Matches))
+	a.Matches++
+	b.Matches++
+	if err := r.saveTx(ctx, tx, aID, a); err != nil {
+		return nil, nil, err
+	}
+	if err := r.saveTx(ctx, tx, bID, b); err != nil {
+		return nil, nil, err
+	}
+	return a, b, nil
+}
+
+// lockTx selects the user's rating FOR UPDATE, creating it at the default if missing
+func (r *RatingRepo) lockTx(ctx context.Context, tx pgx.Tx, userID, gameID string) (*Rating, error) {
+	const ins = `INSERT INTO user_ratings (user_id, game_id, rating) VALUES ($1, $2, $3) ON CONFLICT (user_id, game_id) DO NOTHING`
+	if _, err := tx.Exec(ctx, ins, userID, gameID, game.DefaultRating); err != nil {
+		return nil, err
+	}
+	out := &Rating{GameID: gameID}
+	const sel = `SELECT rating, matches FROM user_ratings WHERE user_id = $1 AND game_id = $2 FOR UPDATE`
+	if err := tx.QueryRow(ctx, sel, userID, gameID).Scan(&out.Rating, &out.Matches); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *RatingRepo) saveTx(ctx context.Context, tx pgx.Tx, userID string, rating *Rating) error {
+	const q = `UPDATE user_ratings SET rating = $3, matches = $4, updated_at = now() WHERE user_id = $1 AND game_id = $2`
+	_, err := tx.Exec(ctx, q, userID, rating.GameID, rating.Rating, rating.Matches)
+	return err
+}
@@ -42,3 +42,117 @@ type Game struct {
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 }
+
+type InventoryItem struct {
+	ItemID    string    `json:"item_id"`
+	Quantity  int       `json:"quantity"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type GameSession struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	GameID     string     `json:"game_id"`
+	Status     string     `json:"status"` // "open" | "finished" | "rejected"
+	Score      *int       `json:"score,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+type GamePlay struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	GameID     string    `json:"game_id"`
+	Score      int       `json:"score"`
+	DurationMs int       `json:"duration_ms"`
+	Mode       string    `json:"mode"`                  // "solo" | "multiplayer"
+	OpponentID *string   `json:"opponent_id,omitempty"` // Multiplayer only
+	Outcome    *string   `json:"outcome,omitempty"`     // "win" | "loss" | "draw", multiplayer only
+	SessionID  *string   `json:"session_id,omitempty"`
+	MatchID    *string   `json:"match_id,omitempty"` // Multiplayer only
+	StartedAt  time.Time `json:"started_at"`
+	EndedAt    time.Time `json:"ended_at"`
+}
+
+// Match is a finished multiplayer match
+type Match struct {
+	ID         string        `json:"id"`
+	GameID     string        `json:"game_id"`
+	RoomID     string        `json:"room_id"`
+	Private    bool          `json:"private"`
+	Rounds     int           `json:"rounds"`
+	WinnerID   *string       `json:"winner_id,omitempty"` // Nil for a draw
+	StartedAt  time.Time     `json:"started_at"`
+	EndedAt    time.Time     `json:"ended_at"`
+	DurationMs int           `json:"duration_ms"`
+	Players    []MatchPlayer `json:"players"`
+}
+
+// MatchPlayer is one participant's result in a match
+type MatchPlayer struct {
+	Place   int       `json:"place"` // 1 is the best
+	UserID  string    `json:"user_id"`
+	Score   int       `json:"score"`
+	Outcome string    `json:"outcome"`
+	Forfeit bool      `json:"forfeit"`
+	EndedAt time.Time `json:"ended_at"`
+}
+
+type GamePlayStats struct {
+	GameID   string  `json:"game_id"`
+	Plays    int     `json:"plays"`
+	Average  float64 `json:"average"`
+	Best     int     `json:"best"`
+	Last7Day struct {
+		Plays   int     `json:"plays"`
+		Average float64 `json:"average"`
+		Best    int     `json:"best"`
+	} `json:"last_7_days"`
+}
+
+type LeaderboardEntry struct {
+	Rank        int    `json:"rank"`
+	UserID      string `json:"user_id"`
+	DisplayName string `json:"display_name"`
+	Score       int    `json:"score"`
+}
+
+type EnergyLedgerEntry struct {
+	ID           string    `json:"id"`
+	Delta        int       `json:"delta"`
+	Reason       string    `json:"reason"`
+	RefID        *string   `json:"ref_id,omitempty"`
+	Note         *string   `json:"note,omitempty"`     // Admin corrections only
+	ActorID      *string   `json:"actor_id,omitempty"` // Admin who made the correction
+	BalanceAfter int       `json:"balance_after"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// UserQuest is a quest assigned to a user for one period
+type UserQuest struct {
+	ID           string     `json:"id"`
+	QuestID      string     `json:"quest_id"`
+	Period       string     `json:"period"`
+	PeriodStart  time.Time  `json:"period_start"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	Progress     int        `json:"progress"`
+	Target       int        `json:"target"`
+	RewardCoins  int        `json:"reward_coins"`
+	RewardEnergy int        `json:"reward_energy"`
+	ClaimedAt    *time.Time `json:"claimed_at"`
+}
+
+// DailyLogin is one day on which a user was active, in their time zone
+type DailyLogin struct {
+	Day         time.Time  `json:"day"`
+	Streak      int        `json:"streak"`
+	FirstSeenAt time.Time  `json:"first_seen_at"`
+	ClaimedAt   *time.Time `json:"claimed_at"`
+}
+
+type Rating struct {
+	GameID  string `json:"game_id"`
+	Rating  int    `json:"rating"`
+	Matches int    `json:"matches"` // Rated matches played
+}
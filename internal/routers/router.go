@@ -6,6 +6,8 @@ import (
 	"fsd-backend/internal/auth"
 	"fsd-backend/internal/controllers"
 	"fsd-backend/internal/middleware"
+	"fsd-backend/internal/pet"
+	"fsd-backend/internal/repository"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -31,7 +33,7 @@ func RegisterAPIV1(r *gin.Engine, cfg cfgLike, signer *auth.Signer, pool *pgxpoo
 
 	// protected
 	protected := v1.Group("/")
-	protected.Use(jwtmw.Require())
+	protected.Use(jwtmw.Require(), middleware.RecordLogins(repository.NewLoginRepo(pool)))
 	{
 		protected.GET("/auth/me", authCtl.Me)
 
@@ -42,11 +44,31 @@ func RegisterAPIV1(r *gin.Engine, cfg cfgLike, signer *auth.Signer, pool *pgxpoo
 		protected.PUT("/users/:id/name", udb.UpdateName)
 		protected.DELETE("/users/:id", udb.Delete)
 		protected.GET("/users/me/energy", udb.GetEnergy)
-		protected.PUT("/users/me/energy", udb.UpdateEnergy)
+		protected.GET("/users/me/energy/history", udb.GetEnergyHistory)
+		protected.PUT("/users/me/timezone", udb.UpdateTimezone)
 
-		// pdb := controllers.NewPetControllerDB(pool)
-		// protected.GET("/pets/:id", pdb.GetByID)
-		// protected.POST("/pets", pdb.Create)
+		loginCtl := controllers.NewLoginController(pool)
+		protected.GET("/users/me/daily-login", loginCtl.GetCalendar)
+		protected.POST("/users/me/daily-login/claim", loginCtl.Claim)
+
+		pdb := controllers.NewPetController(pool)
+		protected.GET("/pets/me", pdb.GetMine)
+		protected.GET("/pets/species", pdb.ListSpecies)
+		protected.GET("/pets/care-actions", pdb.ListCareActions)
+		protected.GET("/pets/:id/state", pdb.GetState)
+		protected.GET("/pets/:id/events", pdb.GetEvents)
+		protected.POST("/pets/:id/feed", pdb.Care(pet.CareFeed))
+		protected.POST("/pets/:id/pet", pdb.Care(pet.CarePet))
+		protected.POST("/pets/:id/clean", pdb.Care(pet.CareClean))
+		protected.POST("/pets/:id/sleep", pdb.Care(pet.CareSleep))
+		protected.PUT("/pets/:id/loadout/:slot", pdb.Equip)
+		protected.DELETE("/pets/:id/loadout/:slot", pdb.Unequip)
+
+		sdb := controllers.NewShopController(pool)
+		protected.GET("/shop/items", sdb.ListItems)
+		protected.POST("/shop/buy", sdb.Buy)
+		protected.GET("/inventory", sdb.GetInventory)
+		protected.POST("/inventory/use", sdb.Use)
 
 		hdb := controllers.NewHabitController(pool)
 		protected.GET("/habits", hdb.List)
@@ -60,19 +82,46 @@ func RegisterAPIV1(r *gin.Engine, cfg cfgLike, signer *auth.Signer, pool *pgxpoo
 		// protected.POST("/games", gdb.Create)
 
 		// Game API endpoints for Godot game
-		gameCtl := controllers.NewGameController(pool)
+		gameCtl := controllers.NewGameController(pool, signer)
 		gameGroup := protected.Group("/game")
 		{
+			gameGroup.GET("/catalog", gameCtl.GetCatalog)
 			gameGroup.GET("/data", gameCtl.GetUserData)
+			gameGroup.POST("/sessions", gameCtl.StartSession)
+			gameGroup.POST("/sessions/:id/finish", gameCtl.FinishSession)
 			gameGroup.POST("/save", gameCtl.SaveGameData)
 			gameGroup.POST("/check-energy", gameCtl.CheckEnergy)
-			gameGroup.POST("/deduct-energy", gameCtl.DeductEnergy)
 			gameGroup.GET("/mood", gameCtl.GetMood)
+			gameGroup.GET("/history", gameCtl.GetHistory)
+			gameGroup.GET("/stats", gameCtl.GetStats)
+			gameGroup.GET("/leaderboards/:title", gameCtl.GetLeaderboard)
+			gameGroup.GET("/ratings/:title", gameCtl.GetRating)
+		}
+
+		achCtl := controllers.NewAchievementController(pool)
+		protected.GET("/achievements", achCtl.List)
+
+		questCtl := controllers.NewQuestController(pool)
+		protected.GET("/quests", questCtl.List)
+		protected.POST("/quests/:id/claim", questCtl.Claim)
+
+		// Admin-only corrections
+		adminCtl := controllers.NewAdminController(pool)
+		adminGroup := protected.Group("/admin")
+		adminGroup.Use(middleware.RequireAdmin(repository.NewUserRepo(pool)))
+		{
+			adminGroup.POST("/users/:id/energy", adminCtl.AdjustEnergy)
+			adminGroup.GET("/users/:id/energy/history", adminCtl.GetEnergyHistory)
 		}
 	}
 }
 
-func RegisterWS(r *gin.Engine, cfg cfgLike, signer *auth.Signer) {
+func RegisterWS(r *gin.Engine, cfg cfgLike, signer *auth.Signer, pool *pgxpool.Pool) {
 	r.GET("/ws", controllers.WSHandler)
-	r.GET("/ws/sunny-says", controllers.NewSunnySaysWSHandler(signer).HandleConnection)
+	sunnySays := controllers.NewSunnySaysWSHandler(signer, pool)
+	r.GET("/ws/sunny-says", sunnySays.HandleConnection)
+	r.GET("/ws/sunny-says/spectate", sunnySays.HandleSpectate)
+
+	// Lives next to the WebSockets because it reads the same rooms
+	r.GET("/api/v1/game/sunny-says/rooms", middleware.NewJWT(signer).Require(), sunnySays.ListRooms)
 }